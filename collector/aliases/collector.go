@@ -1,9 +1,13 @@
 package aliases
 
 import (
+	"context"
+	"errors"
 	"log"
+	"strconv"
 
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -12,45 +16,76 @@ import (
 type Collector struct {
 	esClient elasticsearch.Client
 
-	metrics []*metrics.Metric
+	aliasMetric      *metrics.Metric
+	aliasInfoMetric  *metrics.Metric
+	writeIndexMetric *metrics.Metric
 }
 
 // NewCollector returns new metrics collector for index aliases
 func NewCollector(esClient elasticsearch.Client) *Collector {
 	return &Collector{
 		esClient: esClient,
-		metrics: []*metrics.Metric{
-			metrics.New(
-				prometheus.GaugeValue,
-				"indices",
-				"alias",
-				"Static metric with index name, alias and const value = 1",
-				[]string{"cluster", "index", "alias"},
-			),
-		},
+		aliasMetric: metrics.New(
+			prometheus.GaugeValue,
+			"indices",
+			"alias",
+			"Static metric with index name, alias and const value = 1",
+			[]string{"cluster", "index", "alias"},
+		),
+		aliasInfoMetric: metrics.New(
+			prometheus.GaugeValue,
+			"indices",
+			"alias_info",
+			"Index alias definition with const value = 1",
+			[]string{"cluster", "index", "alias", "filtered", "index_routing", "search_routing", "is_write_index"},
+		),
+		writeIndexMetric: metrics.New(
+			prometheus.GaugeValue,
+			"alias",
+			"write_index_info",
+			"Write index of alias with const value = 1",
+			[]string{"cluster", "alias", "index"},
+		),
 	}
 }
 
 // Describe implements prometheus.Collector interface
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
-	for _, metric := range c.metrics {
-		ch <- metric.Desc()
-	}
+	ch <- c.aliasMetric.Desc()
+	ch <- c.aliasInfoMetric.Desc()
+	ch <- c.writeIndexMetric.Desc()
 }
 
 // Collect writes data to metrics channel
-func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
-	indices, err := c.esClient.Aliases()
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	indices, err := c.esClient.Aliases(ctx)
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Println("WARN: skipped fetching aliases: ", err)
+		return
+	}
 	if err != nil {
 		log.Println("ERROR: failed to fetch aliases: ", err)
 		return
 	}
 
-	for _, metric := range c.metrics {
-		for indexName, aliases := range indices {
-			for aliasName := range aliases.Aliases {
-				ch <- prometheus.MustNewConstMetric(metric.Desc(), metric.Type(), 1.0, clusterName, indexName, aliasName)
-			}
+	writeIndices := indices.WriteIndices()
+
+	for indexName, aliases := range indices {
+		for aliasName, alias := range aliases.Aliases {
+			ch <- prometheus.MustNewConstMetric(c.aliasMetric.Desc(), c.aliasMetric.Type(), 1.0, clusterName, indexName, aliasName)
+
+			ch <- prometheus.MustNewConstMetric(
+				c.aliasInfoMetric.Desc(), c.aliasInfoMetric.Type(), 1.0,
+				clusterName, indexName, aliasName,
+				strconv.FormatBool(len(alias.Filter) > 0),
+				alias.IndexRouting,
+				alias.SearchRouting,
+				strconv.FormatBool(writeIndices[aliasName] == indexName),
+			)
 		}
 	}
+
+	for aliasName, indexName := range writeIndices {
+		ch <- prometheus.MustNewConstMetric(c.writeIndexMetric.Desc(), c.writeIndexMetric.Type(), 1.0, clusterName, aliasName, indexName)
+	}
 }
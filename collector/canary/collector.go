@@ -0,0 +1,309 @@
+package canary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Probe operations
+const (
+	OperationIndex  = "index"
+	OperationGet    = "get"
+	OperationSearch = "search"
+)
+
+const defaultInterval = 30 * time.Second
+
+// Config is a canary probes configuration
+type Config struct {
+	// Index is a dedicated canary index, documents are written into it
+	Index string `json:"index"`
+	// DocumentID is an ID of canary document, so the index contains one document per exporter. Default - hostname
+	DocumentID string `json:"document_id"`
+	// Interval is a minimal time between probes. Default - 30s
+	Interval string `json:"interval"`
+	// Timeout is a timeout of each operation. Empty means the ES client timeout
+	Timeout string `json:"timeout"`
+	// Buckets are latency histogram buckets in seconds. Default - prometheus.DefBuckets
+	Buckets []float64     `json:"buckets"`
+	Search  *SearchConfig `json:"search"`
+}
+
+// SearchConfig is a search probe configuration
+type SearchConfig struct {
+	// Index is an index name or multi-target expression. Default - canary index
+	Index string `json:"index"`
+	// Body is a query DSL. Default - match_all query
+	Body json.RawMessage `json:"body"`
+}
+
+// Probe is a compiled canary probe
+type Probe struct {
+	index       string
+	documentID  string
+	interval    time.Duration
+	timeout     time.Duration
+	buckets     []float64
+	searchIndex string
+	searchBody  string
+
+	mu        sync.Mutex
+	sequence  int64
+	success   map[string]bool
+	updatedAt time.Time
+}
+
+type operation struct {
+	name string
+	run  func(ctx context.Context, esClient elasticsearch.Client) error
+}
+
+type canaryDocument struct {
+	Timestamp string `json:"@timestamp"`
+	Exporter  string `json:"exporter"`
+	Sequence  int64  `json:"sequence"`
+}
+
+// NewProbe validates config and returns compiled canary probe
+func NewProbe(config Config) (*Probe, error) {
+	if config.Index == "" || strings.ContainsAny(config.Index, "/?#,*") {
+		return nil, fmt.Errorf("canary: invalid index %q", config.Index)
+	}
+
+	p := &Probe{
+		index:      config.Index,
+		documentID: config.DocumentID,
+		interval:   defaultInterval,
+		buckets:    config.Buckets,
+		success:    make(map[string]bool),
+	}
+
+	if p.documentID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("canary: can't get hostname for document id: %s", err)
+		}
+		p.documentID = hostname
+	}
+
+	var err error
+	if config.Interval != "" {
+		if p.interval, err = time.ParseDuration(config.Interval); err != nil {
+			return nil, fmt.Errorf("canary: %s", err)
+		}
+	}
+	if config.Timeout != "" {
+		if p.timeout, err = time.ParseDuration(config.Timeout); err != nil {
+			return nil, fmt.Errorf("canary: %s", err)
+		}
+	}
+
+	if config.Search != nil {
+		p.searchIndex = config.Search.Index
+		if p.searchIndex == "" {
+			p.searchIndex = config.Index
+		}
+		if strings.ContainsAny(p.searchIndex, "/?#") {
+			return nil, fmt.Errorf("canary: invalid search index %q", p.searchIndex)
+		}
+
+		p.searchBody = `{"size":0,"query":{"match_all":{}}}`
+		if len(config.Search.Body) > 0 {
+			if !json.Valid(config.Search.Body) {
+				return nil, fmt.Errorf("canary: search body is not a valid JSON")
+			}
+			p.searchBody = string(config.Search.Body)
+		}
+	}
+
+	return p, nil
+}
+
+// Collector is a collector of synthetic canary probes.
+// Probes run on scrape, but not more often than configured interval.
+type Collector struct {
+	esClient elasticsearch.Client
+	probe    *Probe
+
+	duration *prometheus.HistogramVec
+	success  *metrics.Metric
+}
+
+// NewCollector returns new canary probes collector
+func NewCollector(esClient elasticsearch.Client, probe *Probe) *Collector {
+	return &Collector{
+		esClient: esClient,
+		probe:    probe,
+
+		duration: metrics.NewHistogramVec(
+			"canary", "duration_seconds",
+			"End-to-end latency of successful canary operations",
+			probe.buckets,
+			[]string{"cluster", "operation"},
+		),
+		success: metrics.New(
+			prometheus.GaugeValue, "canary", "success",
+			"Whether the last canary operation succeeded",
+			[]string{"cluster", "operation"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.duration.Describe(ch)
+	ch <- c.success.Desc()
+}
+
+// Collect writes data to metrics channel
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	for operation, ok := range c.probe.run(ctx, c.esClient, func(operation string, d time.Duration) {
+		c.duration.WithLabelValues(clusterName, operation).Observe(d.Seconds())
+	}) {
+		value := 0.0
+		if ok {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.success.Desc(), c.success.Type(), value, clusterName, operation)
+	}
+
+	c.duration.Collect(ch)
+}
+
+// run runs probes if interval has passed and returns success of the last run per operation
+func (p *Probe) run(ctx context.Context, esClient elasticsearch.Client, observe func(operation string, d time.Duration)) map[string]bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.updatedAt.IsZero() && time.Since(p.updatedAt) < p.interval {
+		return p.copySuccess()
+	}
+	p.updatedAt = time.Now()
+	p.sequence++
+
+	operations := []operation{
+		{OperationIndex, p.indexDocument},
+		{OperationGet, p.getDocument},
+	}
+	if p.searchBody != "" {
+		operations = append(operations, operation{OperationSearch, p.search})
+	}
+
+	for _, operation := range operations {
+		if operation.name == OperationGet && !p.success[OperationIndex] {
+			// there is nothing to read back
+			p.success[OperationGet] = false
+			continue
+		}
+
+		start := time.Now()
+		err := p.withTimeout(ctx, esClient, operation.run)
+		if errors.Is(err, httpclient.ErrCircuitOpen) {
+			// the last result is kept, probe of overloaded cluster is skipped rather than failed
+			log.Printf("WARN: canary %s operation skipped: %s", operation.name, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("ERROR: canary %s operation failed: %s", operation.name, err)
+		} else {
+			observe(operation.name, time.Since(start))
+		}
+		p.success[operation.name] = err == nil
+	}
+
+	return p.copySuccess()
+}
+
+func (p *Probe) withTimeout(ctx context.Context, esClient elasticsearch.Client, f func(ctx context.Context, esClient elasticsearch.Client) error) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	return f(ctx, esClient)
+}
+
+func (p *Probe) documentPath() string {
+	return "/" + p.index + "/_doc/" + url.PathEscape(p.documentID)
+}
+
+func (p *Probe) indexDocument(ctx context.Context, esClient elasticsearch.Client) error {
+	body, err := json.Marshal(canaryDocument{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Exporter:  p.documentID,
+		Sequence:  p.sequence,
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Result string `json:"result"`
+	}
+	if err := esClient.Request(ctx, "PUT", p.documentPath(), bytes.NewReader(body), &resp); err != nil {
+		return err
+	}
+	if resp.Result != "created" && resp.Result != "updated" {
+		return fmt.Errorf("unexpected index result %q", resp.Result)
+	}
+
+	return nil
+}
+
+// getDocument reads canary document back with refresh and checks that it is the one just written
+func (p *Probe) getDocument(ctx context.Context, esClient elasticsearch.Client) error {
+	var resp struct {
+		Found  bool           `json:"found"`
+		Source canaryDocument `json:"_source"`
+	}
+	if err := esClient.Request(ctx, "GET", p.documentPath()+"?refresh=true", nil, &resp); err != nil {
+		return err
+	}
+	if !resp.Found {
+		return fmt.Errorf("canary document not found")
+	}
+	if resp.Source.Sequence != p.sequence {
+		return fmt.Errorf("stale canary document: want sequence %d, got %d", p.sequence, resp.Source.Sequence)
+	}
+
+	return nil
+}
+
+func (p *Probe) search(ctx context.Context, esClient elasticsearch.Client) error {
+	resp, err := esClient.Search(ctx, p.searchIndex, strings.NewReader(p.searchBody))
+	if err != nil {
+		return err
+	}
+	if resp.TimedOut {
+		return fmt.Errorf("search timed out")
+	}
+	if resp.Shards.Failed > 0 {
+		return fmt.Errorf("search failed on %d of %d shards", resp.Shards.Failed, resp.Shards.Total)
+	}
+
+	return nil
+}
+
+func (p *Probe) copySuccess() map[string]bool {
+	result := make(map[string]bool, len(p.success))
+	for operation, ok := range p.success {
+		result[operation] = ok
+	}
+
+	return result
+}
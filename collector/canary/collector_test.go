@@ -0,0 +1,64 @@
+package canary
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+)
+
+func TestProbe_Run(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Request().WithMethod("PUT").WithPath("/canary/_doc/exporter-1").WillReturn(201, `{"result": "created"}`)
+	mockHTTPClient.Get("/canary/_doc/exporter-1?refresh=true").WillReturn(200, `{"found": true, "_source": {"sequence": 1}}`)
+	mockHTTPClient.Post("/logs-*/_search").WillReturn(200, `{"timed_out": false, "_shards": {"total": 2, "failed": 1}}`)
+
+	probe, err := NewProbe(Config{
+		Index:      "canary",
+		DocumentID: "exporter-1",
+		Interval:   "1h",
+		Search:     &SearchConfig{Index: "logs-*"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var observed []string
+	observe := func(operation string, d time.Duration) {
+		observed = append(observed, operation)
+	}
+	esClient := elasticsearch.NewClient(mockHTTPClient)
+
+	want := map[string]bool{OperationIndex: true, OperationGet: true, OperationSearch: false}
+	if got := probe.run(context.Background(), esClient, observe); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Unexpected success: want %v, got %v", want, got)
+	}
+	if want := []string{OperationIndex, OperationGet}; !reflect.DeepEqual(want, observed) {
+		t.Fatalf("Unexpected observed operations: want %v, got %v", want, observed)
+	}
+
+	// the next run is within interval, so cached results are returned
+	observed = nil
+	if got := probe.run(context.Background(), esClient, observe); !reflect.DeepEqual(want, got) || observed != nil {
+		t.Fatalf("Unexpected cached run: success %v, observed %v", got, observed)
+	}
+}
+
+func TestProbe_RunStaleDocument(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Request().WithMethod("PUT").WillReturn(200, `{"result": "updated"}`)
+	mockHTTPClient.Get("/canary/_doc/exporter-1?refresh=true").WillReturn(200, `{"found": true, "_source": {"sequence": 0}}`)
+
+	probe, err := NewProbe(Config{Index: "canary", DocumentID: "exporter-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	want := map[string]bool{OperationIndex: true, OperationGet: false}
+	if got := probe.run(context.Background(), elasticsearch.NewClient(mockHTTPClient), func(string, time.Duration) {}); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Unexpected success: want %v, got %v", want, got)
+	}
+}
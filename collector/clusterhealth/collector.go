@@ -1,8 +1,6 @@
 package clusterhealth
 
 import (
-	"log"
-
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
@@ -19,9 +17,11 @@ var clusterStatuses = map[string]float64{
 var (
 	labelsClusterHealth = []string{"cluster"}
 	labelsIndexHealth   = []string{"cluster", "index"}
+	labelsShardHealth   = []string{"cluster", "index", "shard"}
 
 	subsystemClusterHealth = "cluster_health"
 	subsystemIndexHealth   = "cluster_health_index"
+	subsystemShardHealth   = "cluster_health_shard"
 )
 
 type clusterHealthMetric struct {
@@ -34,19 +34,26 @@ type indexHealthMetric struct {
 	Value func(indexHealth model.ClusterHealthIndex) float64
 }
 
+type shardHealthMetric struct {
+	*metrics.Metric
+	Value func(shardHealth model.ClusterHealthShard) float64
+}
+
 type clusterHealthStatusMetric struct {
 	*metrics.Metric
 	Value  func(clusterHealth *model.ClusterHealth) float64
 	Labels func(clusterName, color string) []string
 }
 
-// Collector is an cluster health metrics collector
+// Collector is an cluster health metrics collector.
+// Cluster health is requested by composite collector, which needs cluster name anyway
 type Collector struct {
-	esClient elasticsearch.Client
+	shardLevel bool
 
 	metrics        []*clusterHealthMetric
 	statusMetric   *clusterHealthStatusMetric
 	indicesMetrics []*indexHealthMetric
+	shardsMetrics  []*shardHealthMetric
 }
 
 func newClusterHealthMetric(name, help string, valueExtractor func(*model.ClusterHealth) float64) *clusterHealthMetric {
@@ -63,10 +70,18 @@ func newIndexHealthMetric(name, help string, valueExtractor func(model.ClusterHe
 	}
 }
 
-// NewCollector returns new cluster health collector
-func NewCollector(esClient elasticsearch.Client) *Collector {
-	return &Collector{
-		esClient: esClient,
+func newShardHealthMetric(name, help string, valueExtractor func(model.ClusterHealthShard) float64) *shardHealthMetric {
+	return &shardHealthMetric{
+		Metric: metrics.New(prometheus.GaugeValue, subsystemShardHealth, name, help, labelsShardHealth),
+		Value:  valueExtractor,
+	}
+}
+
+// NewCollector returns new cluster health collector.
+// If shardLevel is true, health of each shard is exported
+func NewCollector(shardLevel bool) *Collector {
+	c := &Collector{
+		shardLevel: shardLevel,
 
 		metrics: []*clusterHealthMetric{
 			newClusterHealthMetric(
@@ -120,6 +135,16 @@ func NewCollector(esClient elasticsearch.Client) *Collector {
 				"unassigned_shards", "The number of shards that exist in the cluster state, but cannot be found in the cluster itself.",
 				func(clusterHealth *model.ClusterHealth) float64 { return float64(clusterHealth.UnassignedShards) },
 			),
+			newClusterHealthMetric(
+				"active_shards_percent", "The ratio of active shards in the cluster expressed as a percentage.",
+				func(clusterHealth *model.ClusterHealth) float64 { return clusterHealth.ActiveShardsPercentAsNumber },
+			),
+			newClusterHealthMetric(
+				"task_max_waiting_in_queue_seconds", "Time the earliest initiated pending task is waiting for being performed in seconds.",
+				func(clusterHealth *model.ClusterHealth) float64 {
+					return float64(clusterHealth.TaskMaxWaitingInQueueMillis) / 1000
+				},
+			),
 		},
 		statusMetric: &clusterHealthStatusMetric{
 			Metric: metrics.New(prometheus.GaugeValue, subsystemClusterHealth, "status", "Cluster status. 1 = green, 2 = yellow, 3 = red", labelsClusterHealth),
@@ -162,6 +187,55 @@ func NewCollector(esClient elasticsearch.Client) *Collector {
 			),
 		},
 	}
+
+	if shardLevel {
+		c.shardsMetrics = []*shardHealthMetric{
+			newShardHealthMetric(
+				"status", "Shard status. 1 = green, 2 = yellow, 3 = red",
+				func(s model.ClusterHealthShard) float64 { return clusterStatuses[s.Status] },
+			),
+			newShardHealthMetric(
+				"primary_active", "Whether the primary shard is active",
+				func(s model.ClusterHealthShard) float64 {
+					if s.PrimaryActive {
+						return 1
+					}
+					return 0
+				},
+			),
+			newShardHealthMetric(
+				"active_replicas", "The number of active replicas of shard",
+				func(s model.ClusterHealthShard) float64 {
+					if s.PrimaryActive {
+						return float64(s.ActiveShards - 1)
+					}
+					return float64(s.ActiveShards)
+				},
+			),
+			newShardHealthMetric(
+				"relocating_shards", "The number of relocating copies of shard",
+				func(s model.ClusterHealthShard) float64 { return float64(s.RelocatingShards) },
+			),
+			newShardHealthMetric(
+				"initializing_shards", "The number of initializing copies of shard",
+				func(s model.ClusterHealthShard) float64 { return float64(s.InitializingShards) },
+			),
+			newShardHealthMetric(
+				"unassigned_shards", "The number of unassigned copies of shard",
+				func(s model.ClusterHealthShard) float64 { return float64(s.UnassignedShards) },
+			),
+		}
+	}
+
+	return c
+}
+
+// Level returns cluster health level required by collector
+func (c *Collector) Level() elasticsearch.ClusterHealthLevel {
+	if c.shardLevel {
+		return elasticsearch.LevelShards
+	}
+	return elasticsearch.LevelIndices
 }
 
 // Describe implements prometheus.Collector interface
@@ -174,17 +248,15 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 		ch <- metric.Desc()
 	}
 
+	for _, metric := range c.shardsMetrics {
+		ch <- metric.Desc()
+	}
+
 	ch <- c.statusMetric.Desc()
 }
 
-// Collect writes data to metrics channel
-func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
-	resp, err := c.esClient.ClusterHealth(elasticsearch.LevelIndices)
-	if err != nil {
-		log.Println("ERROR: failed to fetch cluster health: ", err)
-		return
-	}
-
+// Collect writes data of cluster health response of Level() to metrics channel
+func (c *Collector) Collect(clusterName string, resp *model.ClusterHealth, ch chan<- prometheus.Metric) {
 	for _, metric := range c.metrics {
 		ch <- prometheus.MustNewConstMetric(
 			metric.Desc(),
@@ -203,6 +275,17 @@ func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
 				clusterName, name,
 			)
 		}
+
+		for shard, shardHealth := range index.Shards {
+			for _, metric := range c.shardsMetrics {
+				ch <- prometheus.MustNewConstMetric(
+					metric.Desc(),
+					metric.Type(),
+					metric.Value(shardHealth),
+					clusterName, name, shard,
+				)
+			}
+		}
 	}
 
 	ch <- prometheus.MustNewConstMetric(
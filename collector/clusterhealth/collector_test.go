@@ -0,0 +1,111 @@
+package clusterhealth
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/testdata"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var fqName = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+// collect returns collected values of given response by metric name with labels except cluster,
+// e.g. `elasticsearch_cluster_health_shard_status{index="some-index",shard="0"}`
+func collect(t *testing.T, c *Collector, body string) map[string]float64 {
+	var resp model.ClusterHealth
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan prometheus.Metric, 1000)
+	c.Collect(resp.ClusterName, &resp, ch)
+	close(ch)
+
+	result := make(map[string]float64)
+	for m := range ch {
+		var metric dto.Metric
+		m.Write(&metric)
+
+		var labels []string
+		for _, label := range metric.Label {
+			if label.GetName() != "cluster" {
+				labels = append(labels, label.GetName()+`="`+label.GetValue()+`"`)
+			}
+		}
+		result[fqName.FindStringSubmatch(m.Desc().String())[1]+"{"+strings.Join(labels, ",")+"}"] = metric.Gauge.GetValue()
+	}
+
+	return result
+}
+
+func assertValues(t *testing.T, got map[string]float64, want map[string]float64) {
+	t.Helper()
+	for key, value := range want {
+		if v, ok := got[key]; !ok || v != value {
+			t.Fatalf("Unexpected %s: want %v, got %v (exported: %t)", key, value, v, ok)
+		}
+	}
+}
+
+func TestCollector_Collect(t *testing.T) {
+	c := NewCollector(false)
+	if c.Level() != elasticsearch.LevelIndices {
+		t.Fatalf("Unexpected level: %s", c.Level())
+	}
+
+	values := collect(t, c, testdata.ClusterHealthShardsBody)
+	assertValues(t, values, map[string]float64{
+		`elasticsearch_cluster_health_status{}`:                                    2,
+		`elasticsearch_cluster_health_active_shards_percent{}`:                     75,
+		`elasticsearch_cluster_health_task_max_waiting_in_queue_seconds{}`:         1.5,
+		`elasticsearch_cluster_health_unassigned_shards{}`:                         1,
+		`elasticsearch_cluster_health_index_status{index="some-index"}`:            2,
+		`elasticsearch_cluster_health_index_unassigned_shards{index="some-index"}`: 1,
+	})
+
+	// shards of the response are ignored without shard level
+	for key := range values {
+		if strings.HasPrefix(key, "elasticsearch_cluster_health_shard_") {
+			t.Fatalf("Unexpected shard level series: %s", key)
+		}
+	}
+}
+
+func TestCollector_Collect_Shards(t *testing.T) {
+	c := NewCollector(true)
+	if c.Level() != elasticsearch.LevelShards {
+		t.Fatalf("Unexpected level: %s", c.Level())
+	}
+
+	assertValues(t, collect(t, c, testdata.ClusterHealthShardsBody), map[string]float64{
+		`elasticsearch_cluster_health_shard_status{index="some-index",shard="0"}`:              1,
+		`elasticsearch_cluster_health_shard_primary_active{index="some-index",shard="0"}`:      1,
+		`elasticsearch_cluster_health_shard_active_replicas{index="some-index",shard="0"}`:     1,
+		`elasticsearch_cluster_health_shard_unassigned_shards{index="some-index",shard="0"}`:   0,
+		`elasticsearch_cluster_health_shard_status{index="some-index",shard="1"}`:              2,
+		`elasticsearch_cluster_health_shard_active_replicas{index="some-index",shard="1"}`:     0,
+		`elasticsearch_cluster_health_shard_unassigned_shards{index="some-index",shard="1"}`:   1,
+		`elasticsearch_cluster_health_shard_initializing_shards{index="some-index",shard="1"}`: 0,
+	})
+}
+
+func TestCollector_Collect_ClusterLevel(t *testing.T) {
+	// cluster level response has no indices, cluster level metrics are exported as is
+	values := collect(t, NewCollector(true), `{"cluster_name": "my-huge-cluster", "status": "green", "active_shards_percent_as_number": 100.0}`)
+
+	assertValues(t, values, map[string]float64{
+		`elasticsearch_cluster_health_status{}`:                1,
+		`elasticsearch_cluster_health_active_shards_percent{}`: 100,
+	})
+	for key := range values {
+		if strings.Contains(key, "index=") {
+			t.Fatalf("Unexpected index level series: %s", key)
+		}
+	}
+}
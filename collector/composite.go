@@ -1,53 +1,142 @@
 package collector
 
 import (
+	"context"
+	"errors"
 	"log"
+	"path"
+	"reflect"
 	"sync"
 
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/aliases"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/canary"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/clusterhealth"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/custom"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/freshness"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indices"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/internal"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/lifecycle"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/nodes"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/query"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/recovery"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/segmentreplication"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/tasks"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/version"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 // ICollector is a metrics collector interface
 type ICollector interface {
 	Describe(ch chan<- *prometheus.Desc)
-	Collect(clusterName string, ch chan<- prometheus.Metric)
+	Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric)
 }
 
 // CompositeCollector collects all ES metrics: cluster, nodes, indices.
 // Implements prometheus.Collector
 type CompositeCollector struct {
-	esClient   elasticsearch.Client
-	collectors []ICollector
+	esClient      elasticsearch.Client
+	clusterHealth *clusterhealth.Collector
+	collectors    []ICollector
+	tracer        *tracing.Tracer
+}
+
+// Config is a composite collector configuration
+type Config struct {
+	// ExportMetricsForAllNodes enables export of stats for all nodes in the cluster instead of local node only
+	ExportMetricsForAllNodes bool
+	// ClusterHealthShards enables export of shard level cluster health
+	ClusterHealthShards bool
+	Indices             indices.Config
+	Tasks               tasks.Config
+	// TrackRecoveries enables tracking of completed shard recoveries
+	TrackRecoveries bool
+	// Lifecycle enables export of ILM (ISM on OpenSearch) state of managed indices
+	Lifecycle bool
+	// CustomMetrics are metrics declared in configuration file, custom collector is disabled if empty
+	CustomMetrics []*custom.Metric
+	// Queries are searches declared in configuration file, query collector is disabled if empty
+	Queries []*query.Query
+	// Freshness are index patterns to export the latest document timestamp for, freshness collector is disabled if empty.
+	// Indices are grouped by Indices.Grouper
+	Freshness []*freshness.Target
+	// Canary is a synthetic probe, canary collector is disabled if nil
+	Canary *canary.Probe
+	// Tracer records spans of scrapes and collectors, tracing is disabled if nil
+	Tracer *tracing.Tracer
+
+	AppVersion string
+	GoVersion  string
+	GitBranch  string
 }
 
 // NewCompositeCollector creates new composite collector
-func NewCompositeCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool, appVersion, goVersion, gitBranch string) *CompositeCollector {
+func NewCompositeCollector(esClient elasticsearch.Client, config Config) *CompositeCollector {
 	collectors := []ICollector{
-		internal.NewCollector(appVersion, goVersion, gitBranch),
-		clusterhealth.NewCollector(esClient),
-		nodes.NewCollector(esClient, exportMetricsForAllNodes),
+		internal.NewCollector(config.AppVersion, config.GoVersion, config.GitBranch),
+		version.NewCollector(esClient),
+		nodes.NewCollector(esClient, config.ExportMetricsForAllNodes),
 		aliases.NewCollector(esClient),
-		indices.NewCollector(esClient),
-		recovery.NewCollector(esClient),
-		tasks.NewCollector(esClient),
+		indices.NewCollector(esClient, config.Indices),
+		recovery.NewCollector(esClient, config.TrackRecoveries),
+		tasks.NewCollector(esClient, config.Tasks),
+		segmentreplication.NewCollector(esClient),
+	}
+
+	if config.Lifecycle {
+		collectors = append(collectors, lifecycle.NewCollector(esClient))
+	}
+
+	if len(config.CustomMetrics) > 0 {
+		collectors = append(collectors, custom.NewCollector(esClient, config.CustomMetrics))
+	}
+
+	if len(config.Queries) > 0 {
+		collectors = append(collectors, query.NewCollector(esClient, config.Queries))
+	}
+
+	if len(config.Freshness) > 0 {
+		collectors = append(collectors, freshness.NewCollector(esClient, config.Freshness, config.Indices.Grouper))
+	}
+
+	if config.Canary != nil {
+		collectors = append(collectors, canary.NewCollector(esClient, config.Canary))
 	}
 
 	return &CompositeCollector{
-		esClient:   esClient,
-		collectors: collectors,
+		esClient:      esClient,
+		clusterHealth: clusterhealth.NewCollector(config.ClusterHealthShards),
+		collectors:    collectors,
+		tracer:        config.Tracer,
+	}
+}
+
+// MetricNames returns names of metrics of built-in collectors with given config, custom metrics of the config
+// are ignored. Custom metrics can't have the same names, registration of the composite collector would fail
+func MetricNames(config Config) map[string]bool {
+	config.CustomMetrics = nil
+
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		NewCompositeCollector(nil, config).Describe(ch)
+		close(ch)
+	}()
+
+	names := make(map[string]bool)
+	for desc := range ch {
+		names[metrics.DescName(desc)] = true
 	}
+
+	return names
 }
 
 // Describe sends the super-set of all possible descriptors of metrics
 func (c *CompositeCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.clusterHealth.Describe(ch)
 	c.forEachCollector(func(collector ICollector) {
 		collector.Describe(ch)
 	})
@@ -55,17 +144,52 @@ func (c *CompositeCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect is called by the Prometheus registry when collecting metrics
 func (c *CompositeCollector) Collect(ch chan<- prometheus.Metric) {
-	clusterHealth, err := c.esClient.ClusterHealth(elasticsearch.LevelCluster)
+	ctx, span := c.tracer.Start(context.Background(), "scrape")
+	defer span.End()
+
+	// cluster health response provides cluster name for all collectors
+	clusterHealth, err := c.fetchClusterHealth(ctx)
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Println("WARN: skipped scrape, can't fetch cluster health: ", err)
+		span.SetError(err)
+		return
+	}
 	if err != nil {
-		log.Println("ERROR: can't fetch cluster name: ", err)
+		log.Println("ERROR: can't fetch cluster health: ", err)
+		span.SetError(err)
 		return
 	}
+	span.SetAttribute("cluster", clusterHealth.ClusterName)
+
+	c.clusterHealth.Collect(clusterHealth.ClusterName, clusterHealth, ch)
 
 	c.forEachCollector(func(collector ICollector) {
-		collector.Collect(clusterHealth.ClusterName, ch)
+		ctx, span := c.tracer.Start(ctx, "collect "+collectorName(collector))
+		defer span.End()
+
+		collector.Collect(ctx, clusterHealth.ClusterName, ch)
 	})
 }
 
+// fetchClusterHealth returns cluster health of the level required by cluster health collector.
+// If the heavier indices or shards level request fails, cluster level health is requested,
+// so cluster level metrics and other collectors are still exported
+func (c *CompositeCollector) fetchClusterHealth(ctx context.Context) (*model.ClusterHealth, error) {
+	level := c.clusterHealth.Level()
+	clusterHealth, err := c.esClient.ClusterHealth(ctx, level)
+	if err == nil || level == elasticsearch.LevelCluster || errors.Is(err, httpclient.ErrCircuitOpen) {
+		return clusterHealth, err
+	}
+
+	log.Printf("ERROR: can't fetch %s level cluster health, falling back to cluster level: %s", level, err)
+	return c.esClient.ClusterHealth(ctx, elasticsearch.LevelCluster)
+}
+
+// collectorName returns name of collector package, e.g. "nodes"
+func collectorName(collector ICollector) string {
+	return path.Base(reflect.TypeOf(collector).Elem().PkgPath())
+}
+
 // runCollectorsConcurrently runs given
 func (c *CompositeCollector) forEachCollector(fn func(ICollector)) {
 	var group sync.WaitGroup
@@ -0,0 +1,288 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/custom"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indexgroup"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indices"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/query"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/tasks"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/testdata"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient/decorator"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const indicesStatsBody = `
+{
+	"_shards": {"total": 2, "successful": 2, "failed": 0},
+	"indices": {
+		"twitter": {"primaries": {"docs": {"count": 10}}, "total": {"docs": {"count": 20}}},
+		"logs-000001": {"primaries": {}, "total": {}},
+		"logs-app-2020.03.14": {"primaries": {}, "total": {}},
+		"logs-app-2020.03.15": {"primaries": {}, "total": {}}
+	}
+}`
+
+// newESClient returns ES client serving test data, responses are created on each request
+func newESClient(decorators ...httpclient.DecoratorFunc) elasticsearch.Client {
+	bodies := map[string]string{
+		"/":                    testdata.InfoBody,
+		"/_cluster/health":     testdata.ClusterHealthIndicesBody,
+		"/_nodes/stats":        testdata.NodesBody,
+		"/_nodes/_local/stats": testdata.NodesBody,
+		"/_aliases":            testdata.AliasesBody,
+		"/_stats":              indicesStatsBody,
+		"/_recovery":           testdata.RecoveryBody,
+		"/_tasks":              testdata.TasksBody,
+		"/*/_ilm/explain":      testdata.ILMExplainBody,
+	}
+
+	return elasticsearch.NewClient(httpclient.Decorate(httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		// stats metrics selected by collectors are dropped from path, filter_path is ignored
+		path := r.URL.Path
+		if i := strings.Index(path, "stats/"); i >= 0 {
+			path = path[:i+len("stats")]
+		}
+		body, ok := bodies[path]
+		if r.URL.Query().Get("level") == "shards" {
+			body = testdata.ClusterHealthShardsBody
+		}
+		if !ok {
+			return &http.Response{StatusCode: 404, Body: ioutil.NopCloser(strings.NewReader(`{}`))}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}), decorators...))
+}
+
+func TestCompositeCollector_Pedantic(t *testing.T) {
+	grouper, err := indexgroup.New([]indexgroup.Rule{
+		{Pattern: `^(logs-[a-z]+)-.*$`, Replacement: "$1-*", LatestIndex: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var allGroups []string
+	for _, group := range indices.StatsGroups {
+		allGroups = append(allGroups, group.Name)
+	}
+
+	configs := map[string]Config{
+		"default": {},
+		"all": {
+			ExportMetricsForAllNodes: true,
+			ClusterHealthShards:      true,
+			Indices: indices.Config{
+				EnabledGroups: allGroups,
+				Grouper:       grouper,
+				TopN:          1,
+				TopNBy:        indices.RankByDocs,
+				MaxSeries:     100,
+				AliasStats:    true,
+			},
+			Tasks: tasks.Config{
+				TopN:                 2,
+				LongRunningThreshold: time.Second,
+			},
+			Lifecycle:       true,
+			TrackRecoveries: true,
+		},
+	}
+
+	for name, config := range configs {
+		registry := prometheus.NewPedanticRegistry()
+		if err := registry.Register(NewCompositeCollector(newESClient(), config)); err != nil {
+			t.Fatalf("Can't register collector with %s config: %s", name, err)
+		}
+
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("Inconsistent metrics with %s config: %s", name, err)
+		}
+		if len(families) == 0 {
+			t.Fatalf("No metrics collected with %s config", name)
+		}
+	}
+}
+
+func TestCompositeCollector_Tracing(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := tracing.NewTracer(tracing.NewJSONExporter(&buf))
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCompositeCollector(newESClient(decorator.TracingDecorator(tracer)), Config{
+		Tracer:  tracer,
+		Indices: indices.Config{EnabledGroups: []string{"docs"}},
+	}))
+	if _, err := registry.Gather(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string]tracing.SpanData)
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var span tracing.SpanData
+		if err := dec.Decode(&span); err != nil {
+			t.Fatalf("Error on decoding exported span: %s", err)
+		}
+		spans[span.Name] = span
+	}
+
+	scrape, ok := spans["scrape"]
+	if !ok || scrape.ParentSpanID != "" || scrape.Attributes["cluster"] != "my-huge-cluster" {
+		t.Fatalf("Unexpected scrape span: %+v", scrape)
+	}
+
+	parents := map[string]string{
+		"elasticsearch GET /_cluster/health": "scrape",
+		"collect nodes":                      "scrape",
+		"collect indices":                    "scrape",
+		"elasticsearch GET /_nodes/stats":    "collect nodes",
+		"elasticsearch GET /_stats":          "collect indices",
+		"elasticsearch GET /_tasks":          "collect tasks",
+	}
+	for name, parentName := range parents {
+		span, parent := spans[name], spans[parentName]
+		if span.TraceID != scrape.TraceID || span.ParentSpanID == "" || span.ParentSpanID != parent.SpanID {
+			t.Fatalf("Span %q is not a child of %q: %+v", name, parentName, span)
+		}
+	}
+}
+
+func TestCompositeCollector_CircuitOpen(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	openNodes := func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+			if strings.HasPrefix(r.URL.Path, "/_nodes") {
+				return nil, fmt.Errorf("/_nodes/stats: %w", httpclient.ErrCircuitOpen)
+			}
+			return c.Do(r)
+		})
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCompositeCollector(newESClient(openNodes), Config{}))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(logs.String(), "WARN: skipped fetching nodes stats") {
+		t.Fatalf("Nodes stats are not reported as skipped: %s", logs.String())
+	}
+	if strings.Contains(logs.String(), "ERROR") {
+		t.Fatalf("Skipped request is reported as failed: %s", logs.String())
+	}
+
+	// other collectors aren't affected
+	for _, family := range families {
+		if family.GetName() == "elasticsearch_indices_alias" {
+			return
+		}
+	}
+	t.Fatal("Aliases are not collected")
+}
+
+func TestMetricNames(t *testing.T) {
+	names := MetricNames(Config{})
+	for _, name := range []string{"elasticsearch_cluster_health_status", "elasticsearch_breakers_tripped"} {
+		if !names[name] {
+			t.Fatalf("Built-in metric %s is not reported", name)
+		}
+	}
+
+	// custom metrics with built-in names are rejected instead of failing registration
+	_, err := custom.NewMetrics([]custom.MetricConfig{
+		{Name: "cluster_health_status", Path: "/_cluster/health", Value: "status"},
+	}, names)
+	if err == nil {
+		t.Fatalf("Error expected for custom metric with built-in name")
+	}
+
+	// queries are checked against built-in metrics, custom metrics against both
+	queries, err := query.NewQueries([]query.Config{
+		{Name: "errors", Index: "logs-*", Hits: &query.HitsConfig{Name: "errors_total"}},
+	}, names)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err = query.NewQueries([]query.Config{
+		{Name: "health", Index: "logs-*", Hits: &query.HitsConfig{Name: "cluster_health_status"}},
+	}, names); err == nil {
+		t.Fatalf("Error expected for query metric with built-in name")
+	}
+	if _, err = custom.NewMetrics([]custom.MetricConfig{
+		{Name: "errors_total", Path: "/_cluster/health", Value: "status"},
+	}, MetricNames(Config{Queries: queries})); err == nil {
+		t.Fatalf("Error expected for custom metric with query metric name")
+	}
+}
+
+func TestCompositeCollector_ClusterHealthFallback(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	var levels []string
+	failDetailedHealth := func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path != "/_cluster/health" {
+				return c.Do(r)
+			}
+			level := r.URL.Query().Get("level")
+			levels = append(levels, level)
+			if level != "cluster" {
+				return &http.Response{StatusCode: 504, Body: ioutil.NopCloser(strings.NewReader(`{}`))}, nil
+			}
+			body := `{"cluster_name": "my-huge-cluster", "status": "yellow", "active_shards_percent_as_number": 75.0}`
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+		})
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCompositeCollector(newESClient(failDetailedHealth), Config{ClusterHealthShards: true}))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(levels, ",") != "shards,cluster" {
+		t.Fatalf("Unexpected cluster health requests: %v", levels)
+	}
+	if !strings.Contains(logs.String(), "ERROR: can't fetch shards level cluster health, falling back to cluster level") {
+		t.Fatalf("Failed shards level request is not reported: %s", logs.String())
+	}
+
+	// cluster level metrics and other collectors are exported with cluster name of the fallback response
+	collected := make(map[string]bool)
+	for _, family := range families {
+		collected[family.GetName()] = true
+		if family.GetName() == "elasticsearch_cluster_health_status" && family.Metric[0].GetGauge().GetValue() != 2 {
+			t.Fatalf("Unexpected cluster status: %v", family.Metric[0])
+		}
+	}
+	for _, name := range []string{"elasticsearch_cluster_health_status", "elasticsearch_cluster_health_active_shards_percent", "elasticsearch_indices_alias"} {
+		if !collected[name] {
+			t.Fatalf("Metric %s is not collected", name)
+		}
+	}
+	if collected["elasticsearch_cluster_health_index_status"] {
+		t.Fatalf("Index level health is collected from cluster level response")
+	}
+}
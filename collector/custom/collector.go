@@ -0,0 +1,266 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRe  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// MetricConfig is a declaration of a metric exported from arbitrary ES JSON endpoint
+type MetricConfig struct {
+	// Name is a metric name without "elasticsearch_" prefix
+	Name string `json:"name"`
+	Help string `json:"help"`
+	// Type is "gauge" (default) or "counter"
+	Type string `json:"type"`
+	// Path is an ES endpoint path with optional query, e.g. "/_nodes/stats/plugins"
+	Path string `json:"path"`
+	// Value is a dot-separated JSON path to metric values, "*" segments iterate over object keys or array elements
+	Value  string        `json:"value"`
+	Labels []LabelConfig `json:"labels"`
+	// Interval is a minimal time between requests, cached values are exported in between. Empty means every scrape
+	Interval string `json:"interval"`
+}
+
+// LabelConfig is a label extraction rule. Label value is taken either from the key
+// matched by Nth wildcard of value path (1-based) or from JSON path,
+// where $N references are replaced by keys matched by wildcards.
+type LabelConfig struct {
+	Name     string `json:"name"`
+	Wildcard int    `json:"wildcard"`
+	Path     string `json:"path"`
+}
+
+// Metric is a compiled custom metric
+type Metric struct {
+	*metrics.Metric
+
+	path     string
+	value    jsonPath
+	labels   []LabelConfig
+	interval time.Duration
+
+	mu        sync.Mutex
+	samples   []sample
+	updatedAt time.Time
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+// NewMetrics validates configs and returns compiled metrics. Reserved are fully-qualified names
+// of built-in metrics, metrics with the same names can't be registered together with them
+func NewMetrics(configs []MetricConfig, reserved map[string]bool) ([]*Metric, error) {
+	result := make([]*Metric, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		m, err := newMetric(config)
+		if err != nil {
+			return nil, fmt.Errorf("custom metric %q: %s", config.Name, err)
+		}
+
+		name := metrics.FQName("", config.Name)
+		if names[name] {
+			return nil, fmt.Errorf("custom metric %q: duplicate metric name", config.Name)
+		}
+		if reserved[name] {
+			return nil, fmt.Errorf("custom metric %q: conflicts with built-in metric %s", config.Name, name)
+		}
+		names[name] = true
+
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+func newMetric(config MetricConfig) (*Metric, error) {
+	if !metricNameRe.MatchString(config.Name) {
+		return nil, fmt.Errorf("invalid metric name")
+	}
+	if strings.HasPrefix(config.Name, "exporter_") {
+		return nil, fmt.Errorf("metric name prefix \"exporter_\" is reserved for metrics of exporter itself")
+	}
+
+	var valueType prometheus.ValueType
+	switch config.Type {
+	case "", "gauge":
+		valueType = prometheus.GaugeValue
+	case "counter":
+		valueType = prometheus.CounterValue
+	default:
+		return nil, fmt.Errorf("unknown metric type %q", config.Type)
+	}
+
+	if !strings.HasPrefix(config.Path, "/") {
+		return nil, fmt.Errorf("endpoint path must start with /")
+	}
+
+	value, err := parseJSONPath(config.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	labelNames := []string{"cluster"}
+	for _, label := range config.Labels {
+		if !labelNameRe.MatchString(label.Name) || label.Name == "cluster" {
+			return nil, fmt.Errorf("invalid label name %q", label.Name)
+		}
+		if (label.Wildcard == 0) == (label.Path == "") {
+			return nil, fmt.Errorf("label %q must have either wildcard or path", label.Name)
+		}
+		if label.Wildcard < 0 || label.Wildcard > value.wildcards() {
+			return nil, fmt.Errorf("label %q references unknown wildcard %d", label.Name, label.Wildcard)
+		}
+		labelNames = append(labelNames, label.Name)
+	}
+
+	var interval time.Duration
+	if config.Interval != "" {
+		if interval, err = time.ParseDuration(config.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Metric{
+		Metric:   metrics.New(valueType, "", config.Name, config.Help, labelNames),
+		path:     config.Path,
+		value:    value,
+		labels:   config.Labels,
+		interval: interval,
+	}, nil
+}
+
+// Collector is a collector of metrics declared in configuration file
+type Collector struct {
+	esClient elasticsearch.Client
+	metrics  []*Metric
+}
+
+// NewCollector returns new custom metrics collector
+func NewCollector(esClient elasticsearch.Client, metrics []*Metric) *Collector {
+	return &Collector{
+		esClient: esClient,
+		metrics:  metrics,
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range c.metrics {
+		ch <- metric.Desc()
+	}
+}
+
+// Collect writes data to metrics channel
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	// endpoints shared by several metrics are requested once per scrape
+	responses := make(map[string]interface{})
+	fetch := func(path string) (interface{}, error) {
+		if doc, ok := responses[path]; ok {
+			return doc, nil
+		}
+
+		var doc interface{}
+		if err := c.esClient.Request(ctx, "GET", path, nil, &doc); err != nil {
+			return nil, err
+		}
+		responses[path] = doc
+
+		return doc, nil
+	}
+
+	for _, metric := range c.metrics {
+		for _, s := range metric.refresh(fetch) {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc(),
+				metric.Type(),
+				s.value,
+				append([]string{clusterName}, s.labelValues...)...,
+			)
+		}
+	}
+}
+
+// refresh returns cached samples or fetches new ones if interval has passed.
+// Last known samples are returned on request failure.
+func (m *Metric) refresh(fetch func(path string) (interface{}, error)) []sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.updatedAt.IsZero() && time.Since(m.updatedAt) < m.interval {
+		return m.samples
+	}
+
+	doc, err := fetch(m.path)
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Printf("WARN: skipped fetching %s for custom metric %s: %s", m.path, m.Desc(), err)
+		return m.samples
+	}
+	if err != nil {
+		log.Printf("ERROR: failed to fetch %s for custom metric %s: %s", m.path, m.Desc(), err)
+		return m.samples
+	}
+
+	m.samples = m.extract(doc)
+	m.updatedAt = time.Now()
+
+	return m.samples
+}
+
+// extract converts JSON document to samples
+func (m *Metric) extract(doc interface{}) []sample {
+	var samples []sample
+	seen := make(map[string]bool)
+
+	for _, match := range m.value.find(doc) {
+		value, ok := toFloat64(match.value)
+		if !ok {
+			continue
+		}
+
+		labelValues := make([]string, len(m.labels))
+		for i, label := range m.labels {
+			if label.Wildcard > 0 {
+				labelValues[i] = match.captures[label.Wildcard-1]
+				continue
+			}
+
+			path, err := parseJSONPath(substituteCaptures(label.Path, match.captures))
+			if err != nil {
+				continue
+			}
+			if found := path.find(doc); len(found) > 0 {
+				labelValues[i], _ = toLabelValue(found[0].value)
+			}
+		}
+
+		// the same label values can't be exported twice, so only the first one in order of JSON path matches is kept
+		key := strings.Join(labelValues, "\xff")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		samples = append(samples, sample{labelValues: labelValues, value: value})
+	}
+
+	return samples
+}
@@ -0,0 +1,140 @@
+package custom
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const clusterStatsDoc = `{"indices": {"count": 42}}`
+
+// newESClient returns client serving test documents and counting requests per path
+func newESClient(requests map[string]int) elasticsearch.Client {
+	return elasticsearch.NewClient(httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		requests[r.URL.Path]++
+
+		// mocked response body can be read only once, so the mock is created per request
+		mockHTTPClient := httpclient.NewClientMock()
+		mockHTTPClient.Get("/_nodes/stats/plugins").WillReturn(200, nodesDoc)
+		mockHTTPClient.Get("/_cluster/stats").WillReturn(200, clusterStatsDoc)
+
+		return mockHTTPClient.Do(r)
+	}))
+}
+
+// collect returns collected values by metric name and label values except cluster
+func collect(c *Collector) map[string]float64 {
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(context.Background(), "test-cluster", ch)
+	close(ch)
+
+	result := make(map[string]float64)
+	for m := range ch {
+		var metric dto.Metric
+		m.Write(&metric)
+
+		key := m.Desc().String()[len(`Desc{fqName: "`):]
+		key = key[:strings.Index(key, `"`)]
+		for _, label := range metric.Label {
+			if label.GetName() != "cluster" {
+				key += " " + label.GetValue()
+			}
+		}
+		result[key] = metric.Gauge.GetValue() + metric.Counter.GetValue()
+	}
+
+	return result
+}
+
+func TestCollector_Collect(t *testing.T) {
+	metrics, err := NewMetrics([]MetricConfig{
+		{
+			Name:   "plugin_requests",
+			Path:   "/_nodes/stats/plugins",
+			Value:  "nodes.*.plugins.foo.requests",
+			Labels: []LabelConfig{{Name: "node", Path: "nodes.$1.name"}},
+		},
+		{
+			// both nodes have the same label values, the one of the first node in key order is kept
+			Name:     "plugin_requests_any",
+			Type:     "counter",
+			Path:     "/_nodes/stats/plugins",
+			Value:    "nodes.*.plugins.foo.requests",
+			Labels:   []LabelConfig{{Name: "plugin", Path: "nodes.$1.plugins.foo.name"}},
+			Interval: "1h",
+		},
+		{
+			Name:  "indices_count",
+			Path:  "/_cluster/stats",
+			Value: "indices.count",
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	requests := make(map[string]int)
+	c := NewCollector(newESClient(requests), metrics)
+
+	want := map[string]float64{
+		"elasticsearch_plugin_requests es-1": 10,
+		"elasticsearch_plugin_requests es-2": 20,
+		"elasticsearch_plugin_requests_any ": 10,
+		"elasticsearch_indices_count":        42,
+	}
+	for i := 0; i < 2; i++ {
+		got := collect(c)
+		if len(got) != len(want) {
+			t.Fatalf("Unexpected samples: want %v, got %v", want, got)
+		}
+		for key, value := range want {
+			if got[key] != value {
+				t.Fatalf("Unexpected %q: want %v, got %v", key, value, got[key])
+			}
+		}
+	}
+
+	// endpoint shared by metrics is requested once per scrape, but not more often than the shortest interval
+	if requests["/_nodes/stats/plugins"] != 2 || requests["/_cluster/stats"] != 2 {
+		t.Fatalf("Unexpected requests: %v", requests)
+	}
+
+	// metric with interval only is served from cache on the second scrape
+	cached := NewCollector(newESClient(requests), metrics[1:2])
+	collect(cached)
+	if requests["/_nodes/stats/plugins"] != 2 {
+		t.Fatalf("Cached metric is requested before its interval has passed: %v", requests)
+	}
+}
+
+func TestNewMetrics_Conflicts(t *testing.T) {
+	metric := MetricConfig{Name: "plugin_requests", Path: "/_nodes/stats", Value: "nodes.*.requests"}
+	other := metric
+	other.Name = "exporter_requests"
+
+	cases := []struct {
+		configs  []MetricConfig
+		reserved map[string]bool
+		err      string
+	}{
+		{[]MetricConfig{metric, metric}, nil, `custom metric "plugin_requests": duplicate metric name`},
+		{
+			[]MetricConfig{metric},
+			map[string]bool{"elasticsearch_plugin_requests": true},
+			`custom metric "plugin_requests": conflicts with built-in metric elasticsearch_plugin_requests`,
+		},
+		{[]MetricConfig{other}, nil, `custom metric "exporter_requests": metric name prefix "exporter_" is reserved for metrics of exporter itself`},
+	}
+
+	for _, c := range cases {
+		if _, err := NewMetrics(c.configs, c.reserved); err == nil || err.Error() != c.err {
+			t.Fatalf("Unexpected error: want %q, got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package custom
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed dot-separated path to a value in decoded JSON document.
+// Segment "*" matches any key of an object or any element of an array.
+type jsonPath []string
+
+// jsonMatch is a value found by jsonPath with keys matched by wildcards
+type jsonMatch struct {
+	captures []string
+	value    interface{}
+}
+
+func parseJSONPath(path string) (jsonPath, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty JSON path")
+	}
+
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("empty segment in JSON path %q", path)
+		}
+	}
+
+	return jsonPath(segments), nil
+}
+
+// wildcards returns number of wildcard segments
+func (p jsonPath) wildcards() int {
+	n := 0
+	for _, segment := range p {
+		if segment == "*" {
+			n++
+		}
+	}
+	return n
+}
+
+// find returns all values matching the path. Object keys are walked in sorted order and array elements
+// in their order, so matches are returned in the same order for the same document
+func (p jsonPath) find(doc interface{}) []jsonMatch {
+	var result []jsonMatch
+	p.walk(doc, nil, &result)
+	return result
+}
+
+func (p jsonPath) walk(v interface{}, captures []string, result *[]jsonMatch) {
+	if len(p) == 0 {
+		*result = append(*result, jsonMatch{captures: captures, value: v})
+		return
+	}
+
+	segment, rest := p[0], p[1:]
+
+	switch node := v.(type) {
+	case map[string]interface{}:
+		if segment != "*" {
+			if child, ok := node[segment]; ok {
+				rest.walk(child, captures, result)
+			}
+			return
+		}
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			rest.walk(node[key], appendCapture(captures, key), result)
+		}
+	case []interface{}:
+		if segment != "*" {
+			i, err := strconv.Atoi(segment)
+			if err == nil && i >= 0 && i < len(node) {
+				rest.walk(node[i], captures, result)
+			}
+			return
+		}
+		for i, child := range node {
+			rest.walk(child, appendCapture(captures, strconv.Itoa(i)), result)
+		}
+	}
+}
+
+// appendCapture returns a copy of captures with key appended, so sibling branches don't share backing array
+func appendCapture(captures []string, key string) []string {
+	result := make([]string, len(captures), len(captures)+1)
+	copy(result, captures)
+	return append(result, key)
+}
+
+// substituteCaptures replaces $1..$N references in path with captured keys
+func substituteCaptures(path string, captures []string) string {
+	for i := len(captures); i > 0; i-- {
+		path = strings.Replace(path, "$"+strconv.Itoa(i), captures[i-1], -1)
+	}
+	return path
+}
+
+// toFloat64 converts JSON value to metric value
+func toFloat64(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// toLabelValue converts JSON scalar value to label value
+func toLabelValue(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case string:
+		return value, true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,70 @@
+package custom
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const nodesDoc = `
+{
+	"nodes": {
+		"node-a": {"name": "es-1", "plugins": {"foo": {"requests": 10, "enabled": true}}},
+		"node-b": {"name": "es-2", "plugins": {"foo": {"requests": 20, "enabled": false}}}
+	},
+	"pools": [{"size": 1}, {"size": 2}]
+}`
+
+func TestJSONPath_Find(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(nodesDoc), &doc); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	path, err := parseJSONPath("nodes.*.plugins.foo.requests")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	matches := path.find(doc)
+	sort.Slice(matches, func(a, b int) bool { return matches[a].captures[0] < matches[b].captures[0] })
+
+	want := []jsonMatch{
+		{captures: []string{"node-a"}, value: 10.0},
+		{captures: []string{"node-b"}, value: 20.0},
+	}
+	if !reflect.DeepEqual(want, matches) {
+		t.Fatalf("Unexpected matches: want %+v, got %+v", want, matches)
+	}
+
+	label, _ := parseJSONPath(substituteCaptures("nodes.$1.name", matches[1].captures))
+	if got := label.find(doc); len(got) != 1 || got[0].value != "es-2" {
+		t.Fatalf("Unexpected label matches: %+v", got)
+	}
+}
+
+func TestJSONPath_FindArray(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(nodesDoc), &doc); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	path, _ := parseJSONPath("pools.*.size")
+	if got := path.find(doc); len(got) != 2 || got[1].captures[0] != "1" || got[1].value != 2.0 {
+		t.Fatalf("Unexpected matches: %+v", got)
+	}
+
+	path, _ = parseJSONPath("pools.0.size")
+	if got := path.find(doc); len(got) != 1 || got[0].value != 1.0 {
+		t.Fatalf("Unexpected matches: %+v", got)
+	}
+}
+
+func TestParseJSONPath_Invalid(t *testing.T) {
+	for _, path := range []string{"", "nodes..name", "nodes."} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Fatalf("Error expected for path %q, got nil", path)
+		}
+	}
+}
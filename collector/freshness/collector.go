@@ -0,0 +1,228 @@
+package freshness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indexgroup"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultTimestampField = "@timestamp"
+	defaultMaxIndices     = 1000
+
+	indicesAggregation = "indices"
+	latestAggregation  = "latest"
+)
+
+// Config is a declaration of indices to check data freshness for
+type Config struct {
+	// Index is an index name, data stream or multi-target expression, e.g. "logs-*"
+	Index string `json:"index"`
+	// TimestampField is a date field of documents. Default - "@timestamp"
+	TimestampField string `json:"timestamp_field"`
+	// MaxIndices is a maximal number of indices matching the pattern. Default - 1000
+	MaxIndices int `json:"max_indices"`
+	// Timeout is a request timeout. Empty means the ES client timeout
+	Timeout string `json:"timeout"`
+	// Interval is a minimal time between requests, cached values are exported in between. Empty means every scrape
+	Interval string `json:"interval"`
+}
+
+// Target is a compiled freshness check
+type Target struct {
+	index    string
+	body     string
+	timeout  time.Duration
+	interval time.Duration
+
+	mu        sync.Mutex
+	latest    map[string]float64
+	updatedAt time.Time
+}
+
+// NewTargets validates configs and returns compiled freshness checks
+func NewTargets(configs []Config) ([]*Target, error) {
+	result := make([]*Target, 0, len(configs))
+	for _, config := range configs {
+		t, err := newTarget(config)
+		if err != nil {
+			return nil, fmt.Errorf("freshness %q: %s", config.Index, err)
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func newTarget(config Config) (*Target, error) {
+	if config.Index == "" || strings.ContainsAny(config.Index, "/?#") {
+		return nil, fmt.Errorf("invalid index")
+	}
+	if config.TimestampField == "" {
+		config.TimestampField = defaultTimestampField
+	}
+	if config.MaxIndices == 0 {
+		config.MaxIndices = defaultMaxIndices
+	}
+	if config.MaxIndices < 0 {
+		return nil, fmt.Errorf("max_indices must be positive")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			indicesAggregation: map[string]interface{}{
+				// indices with the latest documents are kept when there are more of them than max_indices
+				"terms": map[string]interface{}{
+					"field": "_index",
+					"size":  config.MaxIndices,
+					"order": map[string]interface{}{latestAggregation: "desc"},
+				},
+				"aggs": map[string]interface{}{
+					latestAggregation: map[string]interface{}{"max": map[string]interface{}{"field": config.TimestampField}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Target{
+		index: config.Index,
+		body:  string(body),
+	}
+
+	if config.Timeout != "" {
+		if t.timeout, err = time.ParseDuration(config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	if config.Interval != "" {
+		if t.interval, err = time.ParseDuration(config.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// Collector is a collector of the latest document timestamps
+type Collector struct {
+	esClient elasticsearch.Client
+	targets  []*Target
+	grouper  *indexgroup.Grouper
+
+	latestDocumentTimestamp *metrics.Metric
+}
+
+// NewCollector returns new data freshness collector.
+// Indices are reported by group names of the grouper, which may be nil.
+func NewCollector(esClient elasticsearch.Client, targets []*Target, grouper *indexgroup.Grouper) *Collector {
+	return &Collector{
+		esClient: esClient,
+		targets:  targets,
+		grouper:  grouper,
+
+		latestDocumentTimestamp: metrics.New(
+			prometheus.GaugeValue, "index", "latest_document_timestamp_seconds",
+			"Timestamp of the latest document in index or index group",
+			[]string{"cluster", "index"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.latestDocumentTimestamp.Desc()
+}
+
+// Collect writes data to metrics channel
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	// patterns may overlap, so the latest timestamp is merged across all of them
+	latest := make(map[string]float64)
+	for _, t := range c.targets {
+		for index, timestamp := range t.refresh(ctx, c.esClient) {
+			group, _, _ := c.grouper.Group(index)
+			if current, ok := latest[group]; !ok || timestamp > current {
+				latest[group] = timestamp
+			}
+		}
+	}
+
+	for group, timestamp := range latest {
+		ch <- prometheus.MustNewConstMetric(
+			c.latestDocumentTimestamp.Desc(),
+			c.latestDocumentTimestamp.Type(),
+			timestamp,
+			clusterName, group,
+		)
+	}
+}
+
+// refresh returns cached timestamps or runs the search if interval has passed.
+// Last known timestamps are returned on request failure.
+func (t *Target) refresh(ctx context.Context, esClient elasticsearch.Client) map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.updatedAt.IsZero() && time.Since(t.updatedAt) < t.interval {
+		return t.latest
+	}
+
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	resp, err := esClient.Search(ctx, t.index, strings.NewReader(t.body))
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Printf("WARN: skipped fetching the latest document timestamps of %s: %s", t.index, err)
+		return t.latest
+	}
+	if err != nil {
+		log.Printf("ERROR: failed to fetch the latest document timestamps of %s: %s", t.index, err)
+		return t.latest
+	}
+
+	t.latest = latestTimestamps(resp)
+	t.updatedAt = time.Now()
+
+	return t.latest
+}
+
+// latestTimestamps returns the latest document timestamp in seconds per index.
+// Indices without documents having the timestamp field are skipped.
+func latestTimestamps(resp *model.SearchResponse) map[string]float64 {
+	result := make(map[string]float64)
+
+	agg, _ := resp.Aggregations[indicesAggregation].(map[string]interface{})
+	buckets, _ := agg["buckets"].([]interface{})
+	for _, bucket := range buckets {
+		b, ok := bucket.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index, _ := b["key"].(string)
+		max, _ := b[latestAggregation].(map[string]interface{})
+		// max aggregation of date field returns epoch millis, or null if there are no values
+		if value, ok := max["value"].(float64); ok && index != "" {
+			result[index] = value / 1000
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,113 @@
+package freshness
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indexgroup"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/testdata"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLatestTimestamps(t *testing.T) {
+	body := `
+{
+	"hits": {"total": {"value": 100, "relation": "eq"}, "hits": []},
+	"aggregations": {
+		"indices": {
+			"buckets": [
+				{"key": "logs-app-2020.03.15", "doc_count": 60, "latest": {"value": 1584275400000, "value_as_string": "2020-03-15T12:30:00.000Z"}},
+				{"key": "logs-app-2020.03.14", "doc_count": 40, "latest": {"value": 1584188999500, "value_as_string": "2020-03-14T12:29:59.500Z"}},
+				{"key": "logs-empty", "doc_count": 3, "latest": {"value": null}}
+			]
+		}
+	}
+}`
+
+	var resp model.SearchResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{
+		"logs-app-2020.03.15": 1584275400,
+		"logs-app-2020.03.14": 1584188999.5,
+	}
+	if got := latestTimestamps(&resp); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Unexpected timestamps: want %v, got %v", want, got)
+	}
+}
+
+func TestCollector_Collect(t *testing.T) {
+	targets, err := NewTargets([]Config{{Index: "logs-*", MaxIndices: 2}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	grouper, err := indexgroup.New([]indexgroup.Rule{
+		{Pattern: `(logs-app)-\d{4}\.\d{2}\.\d{2}`, Replacement: "$1-*"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var body map[string]interface{}
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.InfoBody)
+	mockHTTPClient.Post("/logs-*/_search").WithChecker(func(r *http.Request) bool {
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		return true
+	}).WillReturn(200, `
+{
+	"hits": {"total": {"value": 100, "relation": "eq"}, "hits": []},
+	"aggregations": {
+		"indices": {
+			"buckets": [
+				{"key": "logs-app-2020.03.15", "doc_count": 50, "latest": {"value": 1584275400000}},
+				{"key": "logs-app-2020.03.14", "doc_count": 40, "latest": {"value": 1584188999500}},
+				{"key": "logs-other", "doc_count": 10, "latest": {"value": 1584100000000}}
+			]
+		}
+	}
+}`)
+
+	c := NewCollector(elasticsearch.NewClient(mockHTTPClient), targets, grouper)
+
+	ch := make(chan prometheus.Metric, 10)
+	c.Collect(context.Background(), "test-cluster", ch)
+	close(ch)
+
+	got := make(map[string]float64)
+	for m := range ch {
+		var metric dto.Metric
+		m.Write(&metric)
+		for _, label := range metric.Label {
+			if label.GetName() == "index" {
+				got[label.GetValue()] = metric.Gauge.GetValue()
+			}
+		}
+	}
+
+	// the latest timestamp of a group is the one of its latest index
+	want := map[string]float64{
+		"logs-app-*": 1584275400,
+		"logs-other": 1584100000,
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("Unexpected timestamps: want %v, got %v", want, got)
+	}
+
+	// indices are ordered by the latest document, so max_indices cuts off the stale ones
+	terms, _ := body["aggs"].(map[string]interface{})["indices"].(map[string]interface{})["terms"].(map[string]interface{})
+	if order := terms["order"]; !reflect.DeepEqual(order, map[string]interface{}{"latest": "desc"}) || terms["size"] != 2.0 {
+		t.Fatalf("Unexpected terms aggregation: %v", terms)
+	}
+}
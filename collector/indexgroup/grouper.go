@@ -0,0 +1,77 @@
+package indexgroup
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Rule is an index grouping rule. Indices matching Pattern are reported
+// as a single group named by Replacement, which may refer to Pattern submatches ($1, ${name}).
+// Pattern must match the whole index name, so the group name doesn't keep unmatched parts of it.
+type Rule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+	// LatestIndex enables additional export of the latest index of the group.
+	// The latest index is the one with lexicographically greatest name,
+	// which is true for date suffixes and rollover counters.
+	LatestIndex bool `json:"latest_index"`
+}
+
+type compiledRule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+// Grouper maps index names to group names using the first matching rule
+type Grouper struct {
+	rules []*compiledRule
+}
+
+// New returns new grouper for given rules
+func New(rules []Rule) (*Grouper, error) {
+	g := &Grouper{}
+	for _, rule := range rules {
+		// pattern is anchored, otherwise unmatched suffix would be kept in group name making a group per index
+		re, err := regexp.Compile(`^(?:` + rule.Pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index group pattern %q: %s", rule.Pattern, err)
+		}
+		if rule.Replacement == "" {
+			return nil, fmt.Errorf("empty replacement for index group pattern %q", rule.Pattern)
+		}
+		g.rules = append(g.rules, &compiledRule{Rule: rule, re: re})
+	}
+
+	return g, nil
+}
+
+// Group returns group name for given index and whether the latest index of the group should be tracked.
+// If index doesn't match any rule, index name is returned as is and ok is false.
+func (g *Grouper) Group(index string) (group string, trackLatest bool, ok bool) {
+	if g == nil {
+		return index, false, false
+	}
+
+	for _, rule := range g.rules {
+		if rule.re.MatchString(index) {
+			return rule.re.ReplaceAllString(index, rule.Replacement), rule.LatestIndex, true
+		}
+	}
+
+	return index, false, false
+}
+
+// TracksLatest checks if any rule requires tracking of the latest index
+func (g *Grouper) TracksLatest() bool {
+	if g == nil {
+		return false
+	}
+
+	for _, rule := range g.rules {
+		if rule.LatestIndex {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package indexgroup
+
+import (
+	"testing"
+)
+
+func TestGrouper_Group(t *testing.T) {
+	grouper, err := New([]Rule{
+		{Pattern: `^(logs-[a-z]+)-\d{4}\.\d{2}\.\d{2}$`, Replacement: "$1-*", LatestIndex: true},
+		{Pattern: `^metrics-.*$`, Replacement: "metrics"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	cases := []struct {
+		index       string
+		group       string
+		trackLatest bool
+		ok          bool
+	}{
+		{"logs-app-2026.10.18", "logs-app-*", true, true},
+		{"logs-app-latest", "logs-app-latest", false, false},
+		{"metrics-host-1", "metrics", false, true},
+		{"twitter", "twitter", false, false},
+	}
+
+	for _, c := range cases {
+		group, trackLatest, ok := grouper.Group(c.index)
+		if group != c.group || trackLatest != c.trackLatest || ok != c.ok {
+			t.Fatalf("Unexpected group for %q: want (%q, %t, %t), got (%q, %t, %t)",
+				c.index, c.group, c.trackLatest, c.ok, group, trackLatest, ok)
+		}
+	}
+}
+
+func TestGrouper_Group_Anchored(t *testing.T) {
+	grouper, err := New([]Rule{
+		{Pattern: `logs-[a-z]+`, Replacement: "logs"},
+		{Pattern: `metrics-[a-z]+-.*`, Replacement: "metrics"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	cases := []struct {
+		index string
+		group string
+		ok    bool
+	}{
+		{"logs-app", "logs", true},
+		// unanchored pattern would match the prefix and give "logs-2026.10.18" group per index
+		{"logs-app-2026.10.18", "logs-app-2026.10.18", false},
+		{"old-logs-app", "old-logs-app", false},
+		{"metrics-host-2026.10.18", "metrics", true},
+		{"metrics-host-2026.10.19", "metrics", true},
+	}
+
+	for _, c := range cases {
+		group, _, ok := grouper.Group(c.index)
+		if group != c.group || ok != c.ok {
+			t.Fatalf("Unexpected group for %q: want (%q, %t), got (%q, %t)", c.index, c.group, c.ok, group, ok)
+		}
+	}
+}
+
+func TestGrouper_Nil(t *testing.T) {
+	var grouper *Grouper
+
+	if group, _, ok := grouper.Group("twitter"); group != "twitter" || ok {
+		t.Fatalf("Nil grouper must return index name as is")
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New([]Rule{{Pattern: "(", Replacement: "x"}}); err == nil {
+		t.Fatalf("Error expected for invalid pattern, got nil")
+	}
+	if _, err := New([]Rule{{Pattern: "logs-.*"}}); err == nil {
+		t.Fatalf("Error expected for empty replacement, got nil")
+	}
+}
@@ -1,31 +1,114 @@
 package indices
 
 import (
+	"context"
+	"errors"
 	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indexgroup"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
-	labelsIndex = []string{"cluster", "index"}
+	labelsIndex       = []string{"cluster", "index"}
+	labelsLatestIndex = []string{"cluster", "index_group", "index"}
+	labelsAlias       = []string{"cluster", "alias"}
 )
 
+// StatsGroup is a group of index stats metrics which can be enabled or disabled as a whole
+type StatsGroup struct {
+	Name             string
+	EnabledByDefault bool
+	// Fields are parts of indices stats response used by the group metrics
+	Fields elasticsearch.StatsFields
+}
+
+// StatsGroups lists all index stats groups in export order
+var StatsGroups = []StatsGroup{
+	{Name: "docs", EnabledByDefault: true, Fields: summaryFields("docs", "docs")},
+	{Name: "store", EnabledByDefault: true, Fields: summaryFields("store", "store")},
+	{Name: "indexing", EnabledByDefault: true, Fields: summaryFields("indexing", "indexing")},
+	{Name: "get", EnabledByDefault: true, Fields: summaryFields("get", "get")},
+	{Name: "search", EnabledByDefault: true, Fields: summaryFields("search", "search")},
+	{Name: "merges", EnabledByDefault: true, Fields: summaryFields("merge", "merges")},
+	{Name: "refresh", EnabledByDefault: true, Fields: summaryFields("refresh", "refresh")},
+	{Name: "flush", EnabledByDefault: false, Fields: summaryFields("flush", "flush")},
+	{Name: "warmer", EnabledByDefault: false, Fields: summaryFields("warmer", "warmer")},
+	{Name: "query_cache", EnabledByDefault: true, Fields: summaryFields("query_cache", "query_cache")},
+	{Name: "request_cache", EnabledByDefault: true, Fields: summaryFields("request_cache", "request_cache")},
+	{Name: "fielddata", EnabledByDefault: true, Fields: summaryFields("fielddata", "fielddata")},
+	{Name: "completion", EnabledByDefault: false, Fields: summaryFields("completion", "completion")},
+	{Name: "segments", EnabledByDefault: true, Fields: summaryFields("segments", "segments")},
+	{Name: "segments_memory", EnabledByDefault: false, Fields: summaryFields("segments", "segments")},
+	{Name: "translog", EnabledByDefault: true, Fields: summaryFields("translog", "translog")},
+	{Name: "recovery", EnabledByDefault: false, Fields: summaryFields("recovery", "recovery")},
+}
+
+// summaryFields returns fields of primaries and total stats of given stats metric.
+// Metric names in request path differ from response field names, e.g. "merge" and "merges"
+func summaryFields(metric, field string) elasticsearch.StatsFields {
+	return elasticsearch.StatsFields{
+		Metrics: []string{metric},
+		Paths:   []string{"primaries." + field, "total." + field},
+	}
+}
+
+// Config is an indices collector configuration
+type Config struct {
+	// EnabledGroups is a list of StatsGroups names to export
+	EnabledGroups []string
+	// Grouper merges stats of indices into groups, e.g. daily indices into one series
+	Grouper *indexgroup.Grouper
+	// TopN limits export of full stats to N indices (or groups) ranked by TopNBy key,
+	// stats of the rest are merged into index="_other". Zero disables the limit
+	TopN   int
+	TopNBy string
+	// MaxSeries is a hard limit of series exported per scrape, the rest is dropped. Zero disables the limit
+	MaxSeries int
+	// AliasStats enables export of stats summed over indices of each alias
+	AliasStats bool
+}
+
 // Collector is a metrics collection with ElasticSearch indices stats
 type Collector struct {
 	esClient elasticsearch.Client
+	grouper  *indexgroup.Grouper
 
 	totalMetrics     []*indexMetric
 	primariesMetrics []*indexMetric
+
+	latestTotalMetrics     []*indexMetric
+	latestPrimariesMetrics []*indexMetric
+
+	aliasTotalMetrics     []*indexMetric
+	aliasPrimariesMetrics []*indexMetric
+
+	topN          int
+	rankKey       rankKey
+	mu            sync.Mutex
+	rankSamples   map[string]float64
+	rankSampledAt time.Time
+
+	maxSeries           int
+	droppedSeries       uint64
+	droppedSeriesMetric *metrics.Metric
+
+	fields elasticsearch.StatsFields
 }
 
 type indexMetric struct {
 	*metrics.Metric
 
-	Value       func(model.IndexSummary) float64
-	LabelValues func(cluster, index string) []string
+	Value     func(model.IndexSummary) float64
+	Aggregate func(a, b float64) float64
+	Versions  elasticsearch.VersionRange
 }
 
 type indexMetricTemplate struct {
@@ -33,6 +116,8 @@ type indexMetricTemplate struct {
 	Name           string
 	Help           string
 	ValueExtractor func(model.IndexSummary) float64
+	Aggregate      func(a, b float64) float64
+	Versions       elasticsearch.VersionRange
 }
 
 func newIndexMetric(t prometheus.ValueType, name, help string, valueExtractor func(model.IndexSummary) float64) *indexMetricTemplate {
@@ -41,145 +126,480 @@ func newIndexMetric(t prometheus.ValueType, name, help string, valueExtractor fu
 		Name:           name,
 		Help:           help,
 		ValueExtractor: valueExtractor,
+		Aggregate:      aggregateSum,
+	}
+}
+
+// aggregatedByMax makes metric values of grouped indices to be aggregated by max instead of sum
+func (t *indexMetricTemplate) aggregatedByMax() *indexMetricTemplate {
+	t.Aggregate = aggregateMax
+	return t
+}
+
+// availableIn limits export of metric to cluster versions where its stats field exists
+func (t *indexMetricTemplate) availableIn(versions elasticsearch.VersionRange) *indexMetricTemplate {
+	t.Versions = versions
+	return t
+}
+
+func aggregateSum(a, b float64) float64 {
+	return a + b
+}
+
+func aggregateMax(a, b float64) float64 {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+func boolToFloat64(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// indexMetricTemplates returns templates of index metrics grouped by StatsGroup name
+func indexMetricTemplates() map[string][]*indexMetricTemplate {
+	return map[string][]*indexMetricTemplate{
+		"docs": {
+			newIndexMetric(
+				prometheus.GaugeValue, "docs_count", "Docs count",
+				func(i model.IndexSummary) float64 { return float64(i.Docs.Count) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "docs_deleted", "Docs deleted",
+				func(i model.IndexSummary) float64 { return float64(i.Docs.Deleted) },
+			),
+		},
+		"store": {
+			newIndexMetric(
+				prometheus.GaugeValue, "store_size_bytes", "The size of the store for shards",
+				func(i model.IndexSummary) float64 { return float64(i.Store.SizeInBytes) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "store_throttle_seconds_total", "Cumulative store throttle time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Store.ThrottleTimeInMillis) / 1000 },
+			).availableIn(elasticsearch.Until("6.0")),
+		},
+		"indexing": {
+			newIndexMetric(
+				prometheus.CounterValue, "indexing_index_total", "Total index calls",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.IndexTotal) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "indexing_index_seconds_total", "Cumulative indexing time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.IndexTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "indexing_index_current", "Number of currently running index operations",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.IndexCurrent) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "indexing_index_failed_total", "Total failed index calls",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.IndexFailed) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "indexing_delete_total", "Total delete calls",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.DeleteTotal) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "indexing_delete_seconds_total", "Cumulative delete time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.DeleteTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "indexing_delete_current", "Number of currently running delete operations",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.DeleteCurrent) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "indexing_noop_update_total", "Total noop updates",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.NoopUpdateTotal) },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "indexing_is_throttled", "Whether indexing is throttled. 1 = throttled, 0 = not throttled",
+				func(i model.IndexSummary) float64 { return boolToFloat64(i.Indexing.IsThrottled) },
+			).aggregatedByMax(),
+			newIndexMetric(
+				prometheus.CounterValue, "indexing_throttle_seconds_total", "Cumulative throttle time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Indexing.ThrottleTimeInMillis) / 1000 },
+			).availableIn(elasticsearch.Since("2.0")),
+		},
+		"get": {
+			newIndexMetric(
+				prometheus.CounterValue, "get_total", "Total get calls",
+				func(i model.IndexSummary) float64 { return float64(i.Get.Total) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "get_seconds_total", "Cumulative get time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Get.TimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "get_exists_total", "Total get calls for existing documents",
+				func(i model.IndexSummary) float64 { return float64(i.Get.ExistsTotal) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "get_exists_seconds_total", "Cumulative get time for existing documents in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Get.ExistsTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "get_missing_total", "Total get calls for missing documents",
+				func(i model.IndexSummary) float64 { return float64(i.Get.MissingTotal) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "get_missing_seconds_total", "Cumulative get time for missing documents in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Get.MissingTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "get_current", "Number of currently running get operations",
+				func(i model.IndexSummary) float64 { return float64(i.Get.Current) },
+			),
+		},
+		"search": {
+			newIndexMetric(
+				prometheus.GaugeValue, "search_open_contexts", "Number of open search contexts",
+				func(i model.IndexSummary) float64 { return float64(i.Search.OpenContexts) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "search_query_time_seconds", "Total search query time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Search.QueryTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "search_query_total", "Total number of search queries",
+				func(i model.IndexSummary) float64 { return float64(i.Search.QueryTotal) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "search_query_current", "Number of currently running search queries",
+				func(i model.IndexSummary) float64 { return float64(i.Search.QueryCurrent) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "search_fetch_time_seconds", "Total search fetch time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Search.FetchTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "search_fetch_total", "Total number of fetches",
+				func(i model.IndexSummary) float64 { return float64(i.Search.FetchTotal) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "search_fetch_current", "Number of currently running fetches",
+				func(i model.IndexSummary) float64 { return float64(i.Search.FetchCurrent) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "search_scroll_time_seconds", "Total search scroll time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Search.ScrollTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "search_scroll_total", "Total number of scrolls",
+				func(i model.IndexSummary) float64 { return float64(i.Search.ScrollTotal) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "search_scroll_current", "Number of currently open scrolls",
+				func(i model.IndexSummary) float64 { return float64(i.Search.ScrollCurrent) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "search_suggest_time_seconds", "Total suggest time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Search.SuggestTimeInMillis) / 1000 },
+			).availableIn(elasticsearch.Since("5.0")),
+			newIndexMetric(
+				prometheus.CounterValue, "search_suggest_total", "Total number of suggests",
+				func(i model.IndexSummary) float64 { return float64(i.Search.SuggestTotal) },
+			).availableIn(elasticsearch.Since("5.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "search_suggest_current", "Number of currently running suggests",
+				func(i model.IndexSummary) float64 { return float64(i.Search.SuggestCurrent) },
+			).availableIn(elasticsearch.Since("5.0")),
+		},
+		"merges": {
+			newIndexMetric(
+				prometheus.GaugeValue, "merges_current", "Number of currently running merges",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.Current) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "merges_current_docs", "Number of docs in currently running merges",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.CurrentDocs) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "merges_current_size_bytes", "Size of currently running merges in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.CurrentSizeInBytes) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "merges_total", "Total merges",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.Total) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "merges_docs_total", "Cumulative docs merged",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.TotalDocs) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "merges_size_bytes", "Merges total size in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.TotalSizeInBytes) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "merges_time_seconds_total", "Total time spent merging in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.TotalTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "merges_stopped_time_seconds_total", "Total time merges were stopped in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.TotalStoppedTimeInMillis) / 1000 },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.CounterValue, "merges_throttled_time_seconds_total", "Total time merges were throttled in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.TotalThrottledTimeInMillis) / 1000 },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "merges_auto_throttle_bytes", "Merges auto throttle rate limit in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Merges.TotalAutoThrottleInBytes) },
+			).aggregatedByMax(),
+		},
+		"refresh": {
+			newIndexMetric(
+				prometheus.CounterValue, "refresh_total", "Total refresh calls",
+				func(i model.IndexSummary) float64 { return float64(i.Refresh.Total) },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.CounterValue, "refresh_time_seconds", "Total refresh time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Refresh.TotalTimeInMillis) / 1000 },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "refresh_listeners", "Number of pending refresh listeners",
+				func(i model.IndexSummary) float64 { return float64(i.Refresh.Listeners) },
+			).availableIn(elasticsearch.Since("5.0")),
+		},
+		"flush": {
+			newIndexMetric(
+				prometheus.CounterValue, "flush_total", "Total flush calls",
+				func(i model.IndexSummary) float64 { return float64(i.Flush.Total) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "flush_periodic_total", "Total periodic flushes",
+				func(i model.IndexSummary) float64 { return float64(i.Flush.Periodic) },
+			).availableIn(elasticsearch.Since("6.3")),
+			newIndexMetric(
+				prometheus.CounterValue, "flush_time_seconds_total", "Total flush time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Flush.TotalTimeInMillis) / 1000 },
+			),
+		},
+		"warmer": {
+			newIndexMetric(
+				prometheus.GaugeValue, "warmer_current", "Number of currently running warmers",
+				func(i model.IndexSummary) float64 { return float64(i.Warmer.Current) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "warmer_total", "Total warmer calls",
+				func(i model.IndexSummary) float64 { return float64(i.Warmer.Total) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "warmer_time_seconds_total", "Total warmer time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Warmer.TotalTimeInMillis) / 1000 },
+			),
+		},
+		"query_cache": {
+			newIndexMetric(
+				prometheus.GaugeValue, "query_cache_memory_size_bytes", "Query cache memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.QueryCache.MemorySizeInBytes) },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.CounterValue, "query_cache_evictions", "Total evictions number from query cache",
+				func(i model.IndexSummary) float64 { return float64(i.QueryCache.Evictions) },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.CounterValue, "query_cache_total_count", "Total lookups in query cache",
+				func(i model.IndexSummary) float64 { return float64(i.QueryCache.TotalCount) },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.CounterValue, "query_cache_hit_count", "Hit count from query cache",
+				func(i model.IndexSummary) float64 { return float64(i.QueryCache.HitCount) },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.CounterValue, "query_cache_miss_count", "Miss count from query cache",
+				func(i model.IndexSummary) float64 { return float64(i.QueryCache.MissCount) },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "query_cache_cache_size", "Number of entries in query cache",
+				func(i model.IndexSummary) float64 { return float64(i.QueryCache.CacheSize) },
+			).availableIn(elasticsearch.Since("2.0")),
+			newIndexMetric(
+				prometheus.CounterValue, "query_cache_cache_count", "Total entries ever added to query cache",
+				func(i model.IndexSummary) float64 { return float64(i.QueryCache.CacheCount) },
+			).availableIn(elasticsearch.Since("2.0")),
+		},
+		"request_cache": {
+			newIndexMetric(
+				prometheus.GaugeValue, "request_cache_memory_size_bytes", "Request cache memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.RequestCache.MemorySizeInBytes) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "request_cache_evictions", "Total evictions number from request cache",
+				func(i model.IndexSummary) float64 { return float64(i.RequestCache.Evictions) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "request_cache_miss_count", "Miss count from request cache",
+				func(i model.IndexSummary) float64 { return float64(i.RequestCache.MissCount) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "request_cache_hit_count", "Hit count from request cache",
+				func(i model.IndexSummary) float64 { return float64(i.RequestCache.HitCount) },
+			),
+		},
+		"fielddata": {
+			newIndexMetric(
+				prometheus.GaugeValue, "fielddata_memory_size_bytes", "Fielddata memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Fielddata.MemorySizeInBytes) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "fielddata_evictions", "Total evictions number from fielddata",
+				func(i model.IndexSummary) float64 { return float64(i.Fielddata.Evictions) },
+			),
+		},
+		"completion": {
+			newIndexMetric(
+				prometheus.GaugeValue, "completion_size_bytes", "Completion suggester memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Completion.SizeInBytes) },
+			),
+		},
+		"segments": {
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_count", "Number of segments",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.Count) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_memory_bytes", "Segments memory in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.MemoryInBytes) },
+			).availableIn(elasticsearch.Until("8.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_index_writer_memory_size_bytes", "Index writer memory usage",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.IndexWriterMemoryInBytes) },
+			),
+		},
+		"segments_memory": {
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_terms_memory_bytes", "Terms memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.TermsMemoryInBytes) },
+			).availableIn(elasticsearch.Until("8.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_stored_fields_memory_bytes", "Stored fields memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.StoredFieldsMemoryInBytes) },
+			).availableIn(elasticsearch.Until("8.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_term_vectors_memory_bytes", "Term vectors memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.TermVectorsMemoryInBytes) },
+			).availableIn(elasticsearch.Until("8.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_norms_memory_bytes", "Norms memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.NormsMemoryInBytes) },
+			).availableIn(elasticsearch.Until("8.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_points_memory_bytes", "Points memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.PointsMemoryInBytes) },
+			).availableIn(elasticsearch.Between("5.0", "8.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_doc_values_memory_bytes", "Doc values memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.DocValuesMemoryInBytes) },
+			).availableIn(elasticsearch.Until("8.0")),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_version_map_memory_bytes", "Version map memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.VersionMapMemoryInBytes) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "segments_fixed_bit_set_memory_bytes", "Fixed bit set memory usage in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Segments.FixedBitSetMemoryInBytes) },
+			),
+		},
+		"translog": {
+			newIndexMetric(
+				prometheus.CounterValue, "translog_operations", "Total translog operations",
+				func(i model.IndexSummary) float64 { return float64(i.Translog.Operations) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "translog_size_in_bytes", "Transolog size in bytes",
+				func(i model.IndexSummary) float64 { return float64(i.Translog.SizeInBytes) },
+			),
+		},
+		"recovery": {
+			newIndexMetric(
+				prometheus.GaugeValue, "recovery_current_as_source", "Number of ongoing recoveries with index shards as source",
+				func(i model.IndexSummary) float64 { return float64(i.Recovery.CurrentAsSource) },
+			),
+			newIndexMetric(
+				prometheus.GaugeValue, "recovery_current_as_target", "Number of ongoing recoveries with index shards as target",
+				func(i model.IndexSummary) float64 { return float64(i.Recovery.CurrentAsTarget) },
+			),
+			newIndexMetric(
+				prometheus.CounterValue, "recovery_throttle_time_seconds_total", "Total recovery throttle time in seconds",
+				func(i model.IndexSummary) float64 { return float64(i.Recovery.ThrottleTimeInMillis) / 1000 },
+			),
+		},
 	}
 }
 
 // NewCollector returns new metrics collection for indices metrics
-func NewCollector(esClient elasticsearch.Client) *Collector {
-	var indexMetricTemplates = []*indexMetricTemplate{
-		newIndexMetric(
-			prometheus.GaugeValue, "docs_count", "Docs count",
-			func(i model.IndexSummary) float64 { return float64(i.Docs.Count) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "docs_deleted", "Docs deleted",
-			func(i model.IndexSummary) float64 { return float64(i.Docs.Deleted) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "store_size_bytes", "The size of the store for shards",
-			func(i model.IndexSummary) float64 { return float64(i.Store.SizeInBytes) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "search_query_time_seconds", "Total search query time in seconds",
-			func(i model.IndexSummary) float64 { return float64(i.Search.QueryTimeInMillis / 1000) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "search_query_total", "Total number of search queries",
-			func(i model.IndexSummary) float64 { return float64(i.Search.QueryTotal) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "search_fetch_time_seconds", "Total search fetch time in seconds",
-			func(i model.IndexSummary) float64 { return float64(i.Search.FetchTimeInMillis / 1000) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "search_fetch_total", "Total number of fetches",
-			func(i model.IndexSummary) float64 { return float64(i.Search.FetchTotal) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "indexing_index_total", "Total index calls",
-			func(i model.IndexSummary) float64 { return float64(i.Indexing.IndexTotal) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "indexing_index_seconds_total", "Cumulative indexing time in seconds",
-			func(i model.IndexSummary) float64 { return float64(i.Indexing.IndexTimeInMillis / 1000) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "indexing_throttle_seconds_total", "Cumulative throttle time in seconds",
-			func(i model.IndexSummary) float64 { return float64(i.Indexing.ThrottleTimeInMillis / 1000) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "segments_count", "Number of segments",
-			func(i model.IndexSummary) float64 { return float64(i.Segments.Count) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "segments_memory_bytes", "Segments memory in bytes",
-			func(i model.IndexSummary) float64 { return float64(i.Segments.MemoryInBytes) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "query_cache_memory_size_bytes", "Query cache memory usage in bytes",
-			func(i model.IndexSummary) float64 { return float64(i.QueryCache.MemorySizeInBytes) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "query_cache_evictions", "Total evictions number from query cache",
-			func(i model.IndexSummary) float64 { return float64(i.QueryCache.Evictions) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "request_cache_memory_size_bytes", "Request cache memory usage in bytes",
-			func(i model.IndexSummary) float64 { return float64(i.RequestCache.MemorySizeInBytes) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "request_cache_evictions", "Total evictions number from request cache",
-			func(i model.IndexSummary) float64 { return float64(i.RequestCache.Evictions) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "request_cache_miss_count", "Miss count from request cache",
-			func(i model.IndexSummary) float64 { return float64(i.RequestCache.MissCount) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "request_cache_hit_count", "Hit count from request cache",
-			func(i model.IndexSummary) float64 { return float64(i.RequestCache.HitCount) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "fielddata_memory_size_bytes", "Fielddata memory usage in bytes",
-			func(i model.IndexSummary) float64 { return float64(i.Fielddata.MemorySizeInBytes) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "fielddata_evictions", "Total evictions number from fielddata",
-			func(i model.IndexSummary) float64 { return float64(i.Fielddata.Evictions) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "segments_index_writer_memory_size_bytes", "Index writer memory usage",
-			func(i model.IndexSummary) float64 { return float64(i.Segments.IndexWriterMemoryInBytes) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "merges_size_bytes", "Merges total size in bytes",
-			func(i model.IndexSummary) float64 { return float64(i.Merges.TotalSizeInBytes) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "refresh_total", "Total refresh calls",
-			func(i model.IndexSummary) float64 { return float64(i.Refresh.Total) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "refresh_time_seconds", "Total refresh time in seconds",
-			func(i model.IndexSummary) float64 { return float64(i.Refresh.TotalTimeInMillis / 1000) },
-		),
-		newIndexMetric(
-			prometheus.CounterValue, "translog_operations", "Total translog operations",
-			func(i model.IndexSummary) float64 { return float64(i.Translog.Operations) },
-		),
-		newIndexMetric(
-			prometheus.GaugeValue, "translog_size_in_bytes", "Transolog size in bytes",
-			func(i model.IndexSummary) float64 { return float64(i.Translog.SizeInBytes) },
-		),
-	}
-
-	var subsystem = "index"
-
-	labelValuesExtractor := func(cluster, index string) []string {
-		return []string{cluster, index}
-	}
-
-	primariesMetrics := make([]*indexMetric, len(indexMetricTemplates))
-	totalMetrics := make([]*indexMetric, len(indexMetricTemplates))
-
-	for i, m := range indexMetricTemplates {
-		primariesMetrics[i] = &indexMetric{
-			Metric:      metrics.New(m.Type, subsystem, "primaries_"+m.Name, m.Help, labelsIndex),
-			Value:       m.ValueExtractor,
-			LabelValues: labelValuesExtractor,
-		}
-		totalMetrics[i] = &indexMetric{
-			Metric:      metrics.New(m.Type, subsystem, "total_"+m.Name, m.Help, labelsIndex),
-			Value:       m.ValueExtractor,
-			LabelValues: labelValuesExtractor,
+func NewCollector(esClient elasticsearch.Client, config Config) *Collector {
+	enabledGroups := make(map[string]bool, len(config.EnabledGroups))
+	for _, name := range config.EnabledGroups {
+		enabledGroups[name] = true
+	}
+
+	var (
+		enabledTemplates []*indexMetricTemplate
+		fields           elasticsearch.StatsFields
+	)
+	templates := indexMetricTemplates()
+	for _, group := range StatsGroups {
+		if enabledGroups[group.Name] {
+			enabledTemplates = append(enabledTemplates, templates[group.Name]...)
+			fields = fields.Merge(group.Fields)
 		}
 	}
+	if config.TopN > 0 {
+		fields = fields.Merge(rankKeys[config.TopNBy].Fields)
+	}
 
-	return &Collector{
+	c := &Collector{
 		esClient:         esClient,
-		primariesMetrics: primariesMetrics,
-		totalMetrics:     totalMetrics,
+		grouper:          config.Grouper,
+		primariesMetrics: newIndexMetrics(enabledTemplates, "index", "primaries_", labelsIndex),
+		totalMetrics:     newIndexMetrics(enabledTemplates, "index", "total_", labelsIndex),
+		topN:             config.TopN,
+		rankKey:          rankKeys[config.TopNBy],
+		maxSeries:        config.MaxSeries,
+		fields:           fields,
+	}
+
+	if config.MaxSeries > 0 {
+		c.droppedSeriesMetric = metrics.New(
+			prometheus.CounterValue,
+			"exporter",
+			"index_series_dropped_total",
+			"Total number of index series dropped because of series limit",
+			[]string{"cluster"},
+		)
+	}
+
+	if config.Grouper.TracksLatest() {
+		c.latestPrimariesMetrics = newIndexMetrics(enabledTemplates, "index_latest", "primaries_", labelsLatestIndex)
+		c.latestTotalMetrics = newIndexMetrics(enabledTemplates, "index_latest", "total_", labelsLatestIndex)
 	}
+
+	if config.AliasStats {
+		c.aliasPrimariesMetrics = newIndexMetrics(enabledTemplates, "alias", "primaries_", labelsAlias)
+		c.aliasTotalMetrics = newIndexMetrics(enabledTemplates, "alias", "total_", labelsAlias)
+	}
+
+	return c
+}
+
+func newIndexMetrics(templates []*indexMetricTemplate, subsystem, prefix string, labels []string) []*indexMetric {
+	result := make([]*indexMetric, len(templates))
+	for i, m := range templates {
+		result[i] = &indexMetric{
+			Metric:    metrics.New(m.Type, subsystem, prefix+m.Name, m.Help, labels),
+			Value:     m.ValueExtractor,
+			Aggregate: m.Aggregate,
+			Versions:  m.Versions,
+		}
+	}
+
+	return result
 }
 
 // Describe implements prometheus.Collector interface
@@ -190,32 +610,149 @@ func (i *Collector) Describe(ch chan<- *prometheus.Desc) {
 	for _, metric := range i.totalMetrics {
 		ch <- metric.Desc()
 	}
+	for _, metric := range i.latestPrimariesMetrics {
+		ch <- metric.Desc()
+	}
+	for _, metric := range i.latestTotalMetrics {
+		ch <- metric.Desc()
+	}
+	for _, metric := range i.aliasPrimariesMetrics {
+		ch <- metric.Desc()
+	}
+	for _, metric := range i.aliasTotalMetrics {
+		ch <- metric.Desc()
+	}
+	if i.droppedSeriesMetric != nil {
+		ch <- i.droppedSeriesMetric.Desc()
+	}
 }
 
 // Collect writes data to metrics channel
-func (i *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
-	res, err := i.esClient.Indices()
+func (i *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	// all stats groups are disabled, empty fields would request the whole stats
+	if i.fields.IsZero() {
+		return
+	}
+
+	version := i.esClient.Version()
+
+	// aliases are fetched before indices, so alias stats are summed while stats are streamed
+	var indexAliases model.Aliases
+	if i.aliasTotalMetrics != nil {
+		var err error
+		if indexAliases, err = i.esClient.Aliases(ctx); errors.Is(err, httpclient.ErrCircuitOpen) {
+			log.Println("WARN: skipped fetching aliases for alias stats: ", err)
+		} else if err != nil {
+			log.Println("ERROR: failed to fetch aliases for alias stats: ", err)
+		}
+	}
+
+	groups := make(map[string]*indexValues)
+	rankValues := make(map[string]float64)
+	latest := make(map[string]latestIndex)
+	aliases := make(map[string]*indexValues)
+
+	err := i.esClient.IndicesStream(ctx, i.fields, func(indexName string, index model.Index) {
+		group, trackLatest, _ := i.grouper.Group(indexName)
+
+		if trackLatest && indexName > latest[group].name {
+			latest[group] = latestIndex{name: indexName, values: newIndexValues(i, index)}
+		}
+
+		mergeValues(i, groups, group, newIndexValues(i, index))
+
+		if i.topN > 0 {
+			rankValues[group] += i.rankKey.Value(index)
+		}
+
+		// indices are summed per alias as is, regardless of grouping and top N
+		for alias := range indexAliases[indexName].Aliases {
+			mergeValues(i, aliases, alias, newIndexValues(i, index))
+		}
+	})
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Println("WARN: skipped fetching indices stats: ", err)
+		return
+	}
 	if err != nil {
 		log.Println("ERROR: failed to fetch indices stats: ", err)
 		return
 	}
 
-	for indexName, index := range res.Indices {
-		for _, metric := range i.primariesMetrics {
-			ch <- prometheus.MustNewConstMetric(
-				metric.Desc(),
-				metric.Type(),
-				metric.Value(index.Primaries),
-				metric.LabelValues(clusterName, indexName)...,
-			)
+	var series, dropped int
+	seriesPerIndex := availableCount(i.primariesMetrics, version) + availableCount(i.totalMetrics, version)
+	allowed := func() bool {
+		if i.maxSeries > 0 && series+seriesPerIndex > i.maxSeries {
+			dropped += seriesPerIndex
+			return false
+		}
+		series += seriesPerIndex
+		return true
+	}
+
+	// series are emitted in stable order, so the same series are dropped by the limit on each scrape
+	ranked := i.rank(groups, rankValues)
+	for _, group := range ranked {
+		if !allowed() {
+			continue
+		}
+		collectValues(ch, version, i.primariesMetrics, groups[group].primaries, clusterName, group)
+		collectValues(ch, version, i.totalMetrics, groups[group].total, clusterName, group)
+	}
+
+	for _, group := range ranked {
+		// latest index of the group merged into "_other" is skipped as well
+		index, ok := latest[group]
+		if !ok || !allowed() {
+			continue
+		}
+		collectValues(ch, version, i.latestPrimariesMetrics, index.values.primaries, clusterName, group, index.name)
+		collectValues(ch, version, i.latestTotalMetrics, index.values.total, clusterName, group, index.name)
+	}
+
+	if i.aliasTotalMetrics != nil {
+		for _, alias := range sortedNames(aliases) {
+			if !allowed() {
+				continue
+			}
+			collectValues(ch, version, i.aliasPrimariesMetrics, aliases[alias].primaries, clusterName, alias)
+			collectValues(ch, version, i.aliasTotalMetrics, aliases[alias].total, clusterName, alias)
 		}
-		for _, metric := range i.totalMetrics {
-			ch <- prometheus.MustNewConstMetric(
-				metric.Desc(),
-				metric.Type(),
-				metric.Value(index.Total),
-				metric.LabelValues(clusterName, indexName)...,
-			)
+	}
+
+	if i.droppedSeriesMetric != nil {
+		total := atomic.AddUint64(&i.droppedSeries, uint64(dropped))
+		ch <- prometheus.MustNewConstMetric(
+			i.droppedSeriesMetric.Desc(),
+			i.droppedSeriesMetric.Type(),
+			float64(total),
+			clusterName,
+		)
+	}
+}
+
+// collectValues sends metric values, metrics which don't exist on the cluster version are skipped
+func collectValues(ch chan<- prometheus.Metric, version elasticsearch.Version, metrics []*indexMetric, values []float64, labelValues ...string) {
+	for n, metric := range metrics {
+		if !metric.Versions.Contains(version) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			metric.Desc(),
+			metric.Type(),
+			values[n],
+			labelValues...,
+		)
+	}
+}
+
+// availableCount returns number of metrics which exist on the cluster version
+func availableCount(metrics []*indexMetric, version elasticsearch.Version) int {
+	count := 0
+	for _, metric := range metrics {
+		if metric.Versions.Contains(version) {
+			count++
 		}
 	}
+	return count
 }
@@ -0,0 +1,300 @@
+package indices
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indexgroup"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// stubClient streams given indices stats and returns given aliases,
+// other methods of elasticsearch.Client are not implemented
+type stubClient struct {
+	elasticsearch.Client
+	indices  map[string]model.Index
+	aliases  model.Aliases
+	requests int
+}
+
+func (c *stubClient) Aliases(ctx context.Context) (model.Aliases, error) {
+	return c.aliases, nil
+}
+
+func (c *stubClient) Version() elasticsearch.Version {
+	return elasticsearch.Version{Distribution: elasticsearch.DistributionElasticsearch, Number: "7.10.2", Major: 7, Minor: 10}
+}
+
+func (c *stubClient) IndicesStream(ctx context.Context, fields elasticsearch.StatsFields, fn func(name string, index model.Index)) error {
+	c.requests++
+	for name, index := range c.indices {
+		fn(name, index)
+	}
+	return nil
+}
+
+type indexStats struct {
+	docs       int64
+	storeSize  int64
+	queries    int64
+	throttled  bool
+	indexTotal int64
+}
+
+func newIndex(s indexStats) model.Index {
+	var index model.Index
+	for _, summary := range []*model.IndexSummary{&index.Primaries, &index.Total} {
+		summary.Docs.Count = s.docs
+		summary.Store.SizeInBytes = s.storeSize
+		summary.Search.QueryTotal = s.queries
+		summary.Indexing.IsThrottled = s.throttled
+		summary.Indexing.IndexTotal = s.indexTotal
+	}
+	return index
+}
+
+var fqName = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+// collect returns collected values by metric name with labels except cluster,
+// e.g. `elasticsearch_index_primaries_docs_count{index="twitter"}`
+func collect(c *Collector) map[string]float64 {
+	ch := make(chan prometheus.Metric, 1000)
+	c.Collect(context.Background(), "test-cluster", ch)
+	close(ch)
+
+	result := make(map[string]float64)
+	for m := range ch {
+		var metric dto.Metric
+		m.Write(&metric)
+
+		var labels []string
+		for _, label := range metric.Label {
+			if label.GetName() != "cluster" {
+				labels = append(labels, label.GetName()+`="`+label.GetValue()+`"`)
+			}
+		}
+		key := fqName.FindStringSubmatch(m.Desc().String())[1] + "{" + strings.Join(labels, ",") + "}"
+
+		switch {
+		case metric.Gauge != nil:
+			result[key] = metric.Gauge.GetValue()
+		case metric.Counter != nil:
+			result[key] = metric.Counter.GetValue()
+		}
+	}
+
+	return result
+}
+
+// indexLabels returns sorted index label values of given metric
+func indexLabels(values map[string]float64, metric string) []string {
+	var result []string
+	for key := range values {
+		if strings.HasPrefix(key, metric+`{index="`) {
+			result = append(result, strings.TrimSuffix(strings.TrimPrefix(key, metric+`{index="`), `"}`))
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func assertValues(t *testing.T, got map[string]float64, want map[string]float64) {
+	t.Helper()
+	for key, value := range want {
+		if v, ok := got[key]; !ok || v != value {
+			t.Fatalf("Unexpected %s: want %v, got %v (exported: %t)", key, value, v, ok)
+		}
+	}
+}
+
+func TestCollector_Groups(t *testing.T) {
+	grouper, err := indexgroup.New([]indexgroup.Rule{
+		{Pattern: `(logs-app)-\d{4}\.\d{2}\.\d{2}`, Replacement: "$1-*", LatestIndex: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	esClient := &stubClient{indices: map[string]model.Index{
+		"logs-app-2020.03.13": newIndex(indexStats{docs: 10}),
+		"logs-app-2020.03.15": newIndex(indexStats{docs: 5, throttled: true}),
+		"logs-app-2020.03.14": newIndex(indexStats{docs: 20}),
+		"twitter":             newIndex(indexStats{docs: 7}),
+	}}
+	c := NewCollector(esClient, Config{Grouper: grouper, EnabledGroups: []string{"docs", "indexing"}})
+
+	values := collect(c)
+
+	// counts are summed, flags are aggregated by max
+	assertValues(t, values, map[string]float64{
+		`elasticsearch_index_primaries_docs_count{index="logs-app-*"}`:                                                 35,
+		`elasticsearch_index_total_indexing_is_throttled{index="logs-app-*"}`:                                          1,
+		`elasticsearch_index_primaries_docs_count{index="twitter"}`:                                                    7,
+		`elasticsearch_index_total_indexing_is_throttled{index="twitter"}`:                                             0,
+		`elasticsearch_index_latest_primaries_docs_count{index="logs-app-2020.03.15",index_group="logs-app-*"}`:        5,
+		`elasticsearch_index_latest_total_indexing_is_throttled{index="logs-app-2020.03.15",index_group="logs-app-*"}`: 1,
+	})
+
+	if got := indexLabels(values, "elasticsearch_index_primaries_docs_count"); strings.Join(got, ",") != "logs-app-*,twitter" {
+		t.Fatalf("Unexpected exported indices: %v", got)
+	}
+	for key := range values {
+		if strings.HasPrefix(key, "elasticsearch_index_latest_") && !strings.Contains(key, `index="logs-app-2020.03.15"`) {
+			t.Fatalf("Unexpected latest index series: %s", key)
+		}
+	}
+}
+
+func TestCollector_TopN(t *testing.T) {
+	indices := map[string]model.Index{
+		"a": newIndex(indexStats{docs: 1, storeSize: 400}),
+		"b": newIndex(indexStats{docs: 5, storeSize: 100}),
+		"c": newIndex(indexStats{docs: 3, storeSize: 300}),
+		"d": newIndex(indexStats{docs: 2, storeSize: 200}),
+	}
+
+	cases := []struct {
+		rankBy string
+		top    string
+		// docs of indices merged into "_other"
+		otherDocs float64
+	}{
+		{RankByDocs, "_other,b,c", 3},
+		{RankByStoreSize, "_other,a,c", 7},
+	}
+
+	for _, tt := range cases {
+		c := NewCollector(&stubClient{indices: indices}, Config{EnabledGroups: []string{"docs"}, TopN: 2, TopNBy: tt.rankBy})
+		values := collect(c)
+
+		if got := indexLabels(values, "elasticsearch_index_primaries_docs_count"); strings.Join(got, ",") != tt.top {
+			t.Fatalf("Unexpected top indices by %s: want %s, got %v", tt.rankBy, tt.top, got)
+		}
+		assertValues(t, values, map[string]float64{
+			`elasticsearch_index_primaries_docs_count{index="_other"}`: tt.otherDocs,
+		})
+	}
+}
+
+func TestCollector_TopN_Rate(t *testing.T) {
+	esClient := &stubClient{indices: map[string]model.Index{
+		"a": newIndex(indexStats{docs: 1, queries: 1000}),
+		"b": newIndex(indexStats{docs: 2, queries: 1000}),
+		"c": newIndex(indexStats{docs: 4, queries: 0}),
+		"d": newIndex(indexStats{docs: 8, queries: 0}),
+	}}
+	c := NewCollector(esClient, Config{EnabledGroups: []string{"docs"}, TopN: 2, TopNBy: RankBySearchRate})
+
+	// the first scrape has no rates, so all indices are ranked equally by name
+	values := collect(c)
+	if got := indexLabels(values, "elasticsearch_index_primaries_docs_count"); strings.Join(got, ",") != "_other,a,b" {
+		t.Fatalf("Unexpected top indices on the first scrape: %v", got)
+	}
+
+	c.rankSampledAt = c.rankSampledAt.Add(-10 * time.Second)
+	esClient.indices = map[string]model.Index{
+		"a": newIndex(indexStats{docs: 1, queries: 1010}),
+		"b": newIndex(indexStats{docs: 2, queries: 1000}),
+		"c": newIndex(indexStats{docs: 4, queries: 500}),
+		"d": newIndex(indexStats{docs: 8, queries: 100}),
+	}
+
+	values = collect(c)
+	if got := indexLabels(values, "elasticsearch_index_primaries_docs_count"); strings.Join(got, ",") != "_other,c,d" {
+		t.Fatalf("Unexpected top indices by search rate: %v", got)
+	}
+	assertValues(t, values, map[string]float64{
+		`elasticsearch_index_primaries_docs_count{index="_other"}`: 3,
+	})
+}
+
+func TestCollector_MaxSeries(t *testing.T) {
+	grouper, err := indexgroup.New([]indexgroup.Rule{
+		{Pattern: `(logs-app)-\d{4}\.\d{2}\.\d{2}`, Replacement: "$1-*", LatestIndex: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alias := func(names ...string) model.AliasInfo {
+		info := model.AliasInfo{Aliases: make(map[string]model.Alias)}
+		for _, name := range names {
+			info.Aliases[name] = model.Alias{}
+		}
+		return info
+	}
+	esClient := &stubClient{
+		indices: map[string]model.Index{
+			"a":                   newIndex(indexStats{docs: 1}),
+			"b":                   newIndex(indexStats{docs: 2}),
+			"c":                   newIndex(indexStats{docs: 3}),
+			"logs-app-2020.03.14": newIndex(indexStats{docs: 4}),
+			"logs-app-2020.03.15": newIndex(indexStats{docs: 5}),
+		},
+		aliases: model.Aliases{
+			"a":                   alias("alias-z"),
+			"b":                   alias("alias-y", "alias-x"),
+			"logs-app-2020.03.15": alias("logs-app"),
+		},
+	}
+	// docs group has 4 series per index: primaries and total docs count and deleted.
+	// 4 groups, the latest index and 4 aliases have 36 series
+	c := NewCollector(esClient, Config{Grouper: grouper, EnabledGroups: []string{"docs"}, MaxSeries: 26, AliasStats: true})
+
+	var first map[string]float64
+	for scrape := 1; scrape <= 10; scrape++ {
+		values := collect(c)
+
+		if got := indexLabels(values, "elasticsearch_index_total_docs_count"); strings.Join(got, ",") != "a,b,c,logs-app-*" {
+			t.Fatalf("Unexpected exported indices: %v", got)
+		}
+		// dropped series are counted across scrapes
+		assertValues(t, values, map[string]float64{
+			`elasticsearch_index_latest_total_docs_count{index="logs-app-2020.03.15",index_group="logs-app-*"}`: 5,
+			`elasticsearch_alias_total_docs_count{alias="alias-x"}`:                                             2,
+			`elasticsearch_exporter_index_series_dropped_total{}`:                                               float64(12 * scrape),
+		})
+
+		// the same series are dropped on each scrape
+		delete(values, `elasticsearch_exporter_index_series_dropped_total{}`)
+		if first == nil {
+			first = values
+		} else if !reflect.DeepEqual(first, values) {
+			t.Fatalf("Exported series changed between scrapes: want %v, got %v", first, values)
+		}
+	}
+}
+
+func TestCollector_NoGroups(t *testing.T) {
+	esClient := &stubClient{indices: map[string]model.Index{"a": newIndex(indexStats{docs: 1})}}
+	c := NewCollector(esClient, Config{AliasStats: true})
+
+	if values := collect(c); len(values) != 0 || esClient.requests != 0 {
+		t.Fatalf("Unexpected indices stats without enabled groups: %d requests, values %v", esClient.requests, values)
+	}
+}
+
+func TestCollector_TimeSeconds(t *testing.T) {
+	index := newIndex(indexStats{docs: 1})
+	index.Primaries.Indexing.IndexTimeInMillis = 1500
+	index.Primaries.Search.QueryTimeInMillis = 250
+	index.Primaries.Refresh.TotalTimeInMillis = 999
+
+	esClient := &stubClient{indices: map[string]model.Index{"twitter": index}}
+	c := NewCollector(esClient, Config{EnabledGroups: []string{"indexing", "search", "refresh"}})
+
+	// milliseconds are converted to seconds without truncation
+	assertValues(t, collect(c), map[string]float64{
+		`elasticsearch_index_primaries_indexing_index_seconds_total{index="twitter"}`: 1.5,
+		`elasticsearch_index_primaries_search_query_time_seconds{index="twitter"}`:    0.25,
+		`elasticsearch_index_primaries_refresh_time_seconds{index="twitter"}`:         0.999,
+	})
+}
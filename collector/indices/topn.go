@@ -0,0 +1,131 @@
+package indices
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
+// otherIndex is an index label value for stats of indices which are not in top N.
+// ES index names can't start with underscore, so there is no collision with real indices.
+const otherIndex = "_other"
+
+// Top N ranking keys
+const (
+	RankByStoreSize    = "store_size"
+	RankByDocs         = "docs"
+	RankBySearchRate   = "search_rate"
+	RankByIndexingRate = "indexing_rate"
+)
+
+type rankKey struct {
+	Value  func(model.Index) float64
+	IsRate bool
+	Fields elasticsearch.StatsFields
+}
+
+var rankKeys = map[string]rankKey{
+	RankByStoreSize: {
+		Value:  func(i model.Index) float64 { return float64(i.Total.Store.SizeInBytes) },
+		Fields: summaryFields("store", "store"),
+	},
+	RankByDocs: {
+		Value:  func(i model.Index) float64 { return float64(i.Primaries.Docs.Count) },
+		Fields: summaryFields("docs", "docs"),
+	},
+	RankBySearchRate: {
+		Value:  func(i model.Index) float64 { return float64(i.Total.Search.QueryTotal) },
+		IsRate: true,
+		Fields: summaryFields("search", "search"),
+	},
+	RankByIndexingRate: {
+		Value:  func(i model.Index) float64 { return float64(i.Total.Indexing.IndexTotal) },
+		IsRate: true,
+		Fields: summaryFields("indexing", "indexing"),
+	},
+}
+
+// Validate checks configuration values
+func (c Config) Validate() error {
+	if c.TopN < 0 {
+		return fmt.Errorf("top N must not be negative, got %d", c.TopN)
+	}
+	if c.MaxSeries < 0 {
+		return fmt.Errorf("max series must not be negative, got %d", c.MaxSeries)
+	}
+	if _, ok := rankKeys[c.TopNBy]; c.TopN > 0 && !ok {
+		return fmt.Errorf("unknown top N ranking key %q", c.TopNBy)
+	}
+
+	return nil
+}
+
+// rank returns names of groups in export order. If top N is enabled,
+// groups out of top N are merged into "_other" group.
+func (i *Collector) rank(groups map[string]*indexValues, rankValues map[string]float64) []string {
+	names := sortedNames(groups)
+	if i.topN <= 0 {
+		return names
+	}
+
+	scores := i.scores(rankValues)
+	sort.Slice(names, func(a, b int) bool {
+		if scores[names[a]] != scores[names[b]] {
+			return scores[names[a]] > scores[names[b]]
+		}
+		return names[a] < names[b]
+	})
+
+	if len(names) <= i.topN {
+		return names
+	}
+
+	other := groups[names[i.topN]]
+	delete(groups, names[i.topN])
+	for _, name := range names[i.topN+1:] {
+		other.merge(i, groups[name])
+		delete(groups, name)
+	}
+	groups[otherIndex] = other
+
+	return append(names[:i.topN:i.topN], otherIndex)
+}
+
+// scores converts ranking values to scores. For rate keys the rate per second
+// since previous scrape is returned, so the first scrape ranks all groups equally.
+func (i *Collector) scores(rankValues map[string]float64) map[string]float64 {
+	if !i.rankKey.IsRate {
+		return rankValues
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(i.rankSampledAt).Seconds()
+
+	scores := make(map[string]float64, len(rankValues))
+	for name, value := range rankValues {
+		if prev, ok := i.rankSamples[name]; ok && elapsed > 0 && value >= prev {
+			scores[name] = (value - prev) / elapsed
+		}
+	}
+
+	i.rankSamples = rankValues
+	i.rankSampledAt = now
+
+	return scores
+}
+
+// sortedNames returns sorted names of index groups or aliases
+func sortedNames(values map[string]*indexValues) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,52 @@
+package indices
+
+import (
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
+// indexValues holds metric values of an index or aggregated values of a group of indices
+type indexValues struct {
+	primaries []float64
+	total     []float64
+}
+
+func newIndexValues(c *Collector, index model.Index) *indexValues {
+	v := &indexValues{
+		primaries: make([]float64, len(c.primariesMetrics)),
+		total:     make([]float64, len(c.totalMetrics)),
+	}
+
+	for n, metric := range c.primariesMetrics {
+		v.primaries[n] = metric.Value(index.Primaries)
+	}
+	for n, metric := range c.totalMetrics {
+		v.total[n] = metric.Value(index.Total)
+	}
+
+	return v
+}
+
+// latestIndex is the latest index of a group with its values
+type latestIndex struct {
+	name   string
+	values *indexValues
+}
+
+// mergeValues aggregates values into values of given key
+func mergeValues(c *Collector, values map[string]*indexValues, key string, other *indexValues) {
+	if v, ok := values[key]; ok {
+		v.merge(c, other)
+	} else {
+		values[key] = other
+	}
+}
+
+// merge aggregates other values into v
+func (v *indexValues) merge(c *Collector, other *indexValues) {
+	for n, metric := range c.primariesMetrics {
+		v.primaries[n] = metric.Aggregate(v.primaries[n], other.primaries[n])
+	}
+	for n, metric := range c.totalMetrics {
+		v.total[n] = metric.Aggregate(v.total[n], other.total[n])
+	}
+}
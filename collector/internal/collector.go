@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"context"
+
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -44,7 +46,7 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 // Collect writes data to metrics channel
-func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(
 		c.buildInfoMetric.Desc(),
 		c.buildInfoMetric.Type(),
@@ -0,0 +1,141 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	labelsIndex     = []string{"cluster", "index", "policy"}
+	labelsIndexInfo = []string{"cluster", "index", "policy", "phase", "action", "step"}
+)
+
+// Collector is a metrics collector for lifecycle state of managed indices.
+// Elasticsearch ILM and OpenSearch ISM are exported as the same metrics, ISM state is exported as phase
+type Collector struct {
+	esClient elasticsearch.Client
+
+	infoMetric           *metrics.Metric
+	failedMetric         *metrics.Metric
+	retriesMetric        *metrics.Metric
+	phaseStartTimeMetric *metrics.Metric
+	stepStartTimeMetric  *metrics.Metric
+}
+
+// NewCollector returns new metrics collector for index lifecycle state
+func NewCollector(esClient elasticsearch.Client) *Collector {
+	return &Collector{
+		esClient: esClient,
+		infoMetric: metrics.New(
+			prometheus.GaugeValue,
+			"index_lifecycle",
+			"info",
+			"Current lifecycle phase (ISM state), action and step of managed index with const value = 1",
+			labelsIndexInfo,
+		),
+		failedMetric: metrics.New(
+			prometheus.GaugeValue,
+			"index_lifecycle",
+			"failed",
+			"Whether lifecycle policy of index failed. 1 = failed, 0 = not failed",
+			labelsIndex,
+		),
+		retriesMetric: metrics.New(
+			prometheus.GaugeValue,
+			"index_lifecycle",
+			"retries",
+			"Number of retries of failed lifecycle step",
+			labelsIndex,
+		),
+		phaseStartTimeMetric: metrics.New(
+			prometheus.GaugeValue,
+			"index_lifecycle",
+			"phase_start_timestamp_seconds",
+			"Time when index entered current lifecycle phase (ISM state) in seconds since epoch",
+			labelsIndex,
+		),
+		stepStartTimeMetric: metrics.New(
+			prometheus.GaugeValue,
+			"index_lifecycle",
+			"step_start_timestamp_seconds",
+			"Time when index entered current lifecycle step in seconds since epoch",
+			labelsIndex,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.infoMetric.Desc()
+	ch <- c.failedMetric.Desc()
+	ch <- c.retriesMetric.Desc()
+	ch <- c.phaseStartTimeMetric.Desc()
+	ch <- c.stepStartTimeMetric.Desc()
+}
+
+// Collect writes data to metrics channel
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	indices, err := c.esClient.Lifecycle(ctx)
+	if errors.Is(err, elasticsearch.ErrNotSupported) {
+		return
+	}
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Println("WARN: skipped fetching index lifecycle state: ", err)
+		return
+	}
+	if err != nil {
+		log.Println("ERROR: failed to fetch index lifecycle state: ", err)
+		return
+	}
+
+	for index, state := range indices {
+		ch <- prometheus.MustNewConstMetric(
+			c.infoMetric.Desc(),
+			c.infoMetric.Type(),
+			1,
+			clusterName, index, state.Policy, state.Phase, state.Action, state.Step,
+		)
+
+		failed := 0.0
+		if state.Failed {
+			failed = 1
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.failedMetric.Desc(),
+			c.failedMetric.Type(),
+			failed,
+			clusterName, index, state.Policy,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.retriesMetric.Desc(),
+			c.retriesMetric.Type(),
+			float64(state.Retries),
+			clusterName, index, state.Policy,
+		)
+
+		if state.PhaseTimeInMillis > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.phaseStartTimeMetric.Desc(),
+				c.phaseStartTimeMetric.Type(),
+				float64(state.PhaseTimeInMillis)/1000,
+				clusterName, index, state.Policy,
+			)
+		}
+
+		if state.StepTimeInMillis > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.stepStartTimeMetric.Desc(),
+				c.stepStartTimeMetric.Type(),
+				float64(state.StepTimeInMillis)/1000,
+				clusterName, index, state.Policy,
+			)
+		}
+	}
+}
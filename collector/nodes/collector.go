@@ -1,10 +1,13 @@
 package nodes
 
 import (
+	"context"
+	"errors"
 	"log"
 
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -15,6 +18,7 @@ var (
 	labelsThreadPool = append(labelsNode, "type")
 	labelsBreaker    = append(labelsNode, "breaker")
 	labelsFilesystem = append(labelsNode, "mount", "path")
+	labelsIODevice   = append(labelsNode, "device")
 	labelsJVMGC      = append(labelsNode, "gc")
 )
 
@@ -29,11 +33,33 @@ var (
 	labelValuesFilesystem = func(cluster string, node model.Node, mount string, path string) []string {
 		return append(labelValuesNode(cluster, node), mount, path)
 	}
+	labelValuesIODevice = func(cluster string, node model.Node, device string) []string {
+		return append(labelValuesNode(cluster, node), device)
+	}
 )
 
+// statsFields lists parts of nodes stats response used by the collector metrics
+var statsFields = elasticsearch.StatsFields{
+	Metrics: []string{"breaker", "fs", "indices", "jvm", "process", "thread_pool", "transport"},
+	Paths: []string{
+		"name", "host",
+		"indices.docs", "indices.store", "indices.indexing", "indices.get", "indices.search", "indices.merges",
+		"indices.fielddata", "indices.query_cache", "indices.request_cache",
+		"indices.flush", "indices.segments", "indices.refresh", "indices.translog",
+		"jvm.mem", "jvm.gc", "process", "transport", "breakers", "thread_pool", "fs.data", "fs.io_stats",
+	},
+}
+
 type nodeMetric struct {
 	*metrics.Metric
-	Value func(node model.Node) float64
+	Value    func(node model.Node) float64
+	Versions elasticsearch.VersionRange
+}
+
+// availableIn limits export of metric to cluster versions where its stats field exists
+func (m *nodeMetric) availableIn(versions elasticsearch.VersionRange) *nodeMetric {
+	m.Versions = versions
+	return m
 }
 
 type gcCollectionMetric struct {
@@ -57,6 +83,11 @@ type filesystemMetric struct {
 	Value func(fsStats model.NodeFSData) float64
 }
 
+type ioDeviceMetric struct {
+	*metrics.Metric
+	Value func(deviceStats model.NodeFSIOStatsDevice) float64
+}
+
 // Collector is an node metrics collector
 type Collector struct {
 	esClient                 elasticsearch.Client
@@ -67,6 +98,7 @@ type Collector struct {
 	breakerMetrics      []*breakerMetric
 	threadPoolMetrics   []*threadPoolMetric
 	filesystemMetrics   []*filesystemMetric
+	ioDeviceMetrics     []*ioDeviceMetric
 }
 
 func newNodeIndexMetric(t prometheus.ValueType, name, help string, valueExtractor func(model.Node) float64) *nodeMetric {
@@ -83,6 +115,20 @@ func newFSGauge(name, help string, valueExtractor func(model.NodeFSData) float64
 	}
 }
 
+func newFSCounter(name, help string, valueExtractor func(model.NodeFSData) float64) *filesystemMetric {
+	return &filesystemMetric{
+		Metric: metrics.New(prometheus.CounterValue, "filesystem_data", name, help, labelsFilesystem),
+		Value:  valueExtractor,
+	}
+}
+
+func newIODeviceCounter(name, help string, valueExtractor func(model.NodeFSIOStatsDevice) float64) *ioDeviceMetric {
+	return &ioDeviceMetric{
+		Metric: metrics.New(prometheus.CounterValue, "filesystem_io_stats_device", name, help, labelsIODevice),
+		Value:  valueExtractor,
+	}
+}
+
 func newThreadPoolMetric(t prometheus.ValueType, name, help string, valueExtractor func(model.ThreadPool) float64) *threadPoolMetric {
 	return &threadPoolMetric{
 		Metric: metrics.New(t, "thread_pool", name, help, labelsThreadPool),
@@ -143,19 +189,19 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			newNodeIndexMetric(
 				prometheus.GaugeValue, "filter_cache_memory_size_bytes", "Filter cache memory usage in bytes",
 				func(n model.Node) float64 { return float64(n.Indices.FilterCache.MemorySize) },
-			),
+			).availableIn(elasticsearch.Until("2.0")),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "filter_cache_evictions", "Evictions from filter cache",
 				func(n model.Node) float64 { return float64(n.Indices.FilterCache.Evictions) },
-			),
+			).availableIn(elasticsearch.Until("2.0")),
 			newNodeIndexMetric(
 				prometheus.GaugeValue, "query_cache_memory_size_bytes", "Query cache memory usage in bytes",
 				func(n model.Node) float64 { return float64(n.Indices.QueryCache.MemorySize) },
-			),
+			).availableIn(elasticsearch.Since("2.0")),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "query_cache_evictions", "Evictions from query cache",
 				func(n model.Node) float64 { return float64(n.Indices.QueryCache.Evictions) },
-			),
+			).availableIn(elasticsearch.Since("2.0")),
 			newNodeIndexMetric(
 				prometheus.GaugeValue, "request_cache_memory_size_bytes", "Request cache memory usage in bytes",
 				func(n model.Node) float64 { return float64(n.Indices.RequestCache.MemorySize) },
@@ -182,7 +228,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "get_time_seconds", "Total get time in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Get.Time / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Get.Time) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "get_total", "Total get",
@@ -190,7 +236,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "get_missing_time_seconds", "Total time of get missing in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Get.MissingTime / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Get.MissingTime) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "get_missing_total", "Total get missing",
@@ -198,7 +244,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "get_exists_time_seconds", "Total time get exists in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Get.ExistsTime / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Get.ExistsTime) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "get_exists_total", "Total get exists operations",
@@ -206,7 +252,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "refresh_time_seconds_total", "Total refreshes",
-				func(n model.Node) float64 { return float64(n.Indices.Refresh.TotalTime / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Refresh.TotalTime) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "refresh_total", "Total time spent refreshing in seconds",
@@ -214,7 +260,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "search_query_time_seconds", "Total search query time in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Search.QueryTime / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Search.QueryTime) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "search_query_total", "Total number of queries",
@@ -222,7 +268,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "search_fetch_time_seconds", "Total search fetch time in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Search.FetchTime / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Search.FetchTime) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "search_fetch_total", "Total number of fetches",
@@ -242,12 +288,12 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "store_throttle_time_seconds_total", "Throttle time for index store in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Store.ThrottleTime / 1000) },
-			),
+				func(n model.Node) float64 { return float64(n.Indices.Store.ThrottleTime) / 1000 },
+			).availableIn(elasticsearch.Until("6.0")),
 			newNodeIndexMetric(
 				prometheus.GaugeValue, "segments_memory_bytes", "Current memory size of segments in bytes",
 				func(n model.Node) float64 { return float64(n.Indices.Segments.Memory) },
-			),
+			).availableIn(elasticsearch.Until("8.0")),
 			newNodeIndexMetric(
 				prometheus.GaugeValue, "segments_count", "Count of index segments on this node",
 				func(n model.Node) float64 { return float64(n.Indices.Segments.Count) },
@@ -258,11 +304,11 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "flush_time_seconds", "Cumulative flush time in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Flush.Time / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Flush.Time) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "indexing_index_time_seconds_total", "Cumulative index time in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Indexing.IndexTime / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Indexing.IndexTime) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "indexing_index_total", "Total index calls",
@@ -270,7 +316,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "indexing_delete_time_seconds_total", "Total time indexing delete in seconds",
-				func(n model.Node) float64 { return float64(n.Indices.Indexing.DeleteTime / 1000) },
+				func(n model.Node) float64 { return float64(n.Indices.Indexing.DeleteTime) / 1000 },
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "indexing_delete_total", "Total indexing deletes",
@@ -290,7 +336,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newNodeIndexMetric(
 				prometheus.CounterValue, "merges_total_time_seconds_total", "Total time spent merging in seconds",
-				func(node model.Node) float64 { return float64(node.Indices.Merges.TotalTime / 1000) },
+				func(node model.Node) float64 { return float64(node.Indices.Merges.TotalTime) / 1000 },
 			),
 
 			newJVMMemoryMetric(
@@ -335,15 +381,15 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newProcessMetric(
 				prometheus.CounterValue, "cpu_time_total_seconds_sum", "Total process CPU time in seconds",
-				func(node model.Node) float64 { return float64(node.Process.CPU.Total / 1000) },
+				func(node model.Node) float64 { return float64(node.Process.CPU.Total) / 1000 },
 			),
 			newProcessMetric(
 				prometheus.CounterValue, "cpu_time_system_seconds_sum", "Process system CPU time in seconds",
-				func(node model.Node) float64 { return float64(node.Process.CPU.Sys / 1000) },
+				func(node model.Node) float64 { return float64(node.Process.CPU.Sys) / 1000 },
 			),
 			newProcessMetric(
 				prometheus.CounterValue, "cpu_time_user_seconds_sum", "Process CPU time in seconds",
-				func(node model.Node) float64 { return float64(node.Process.CPU.User / 1000) },
+				func(node model.Node) float64 { return float64(node.Process.CPU.User) / 1000 },
 			),
 			newTransportMetric(
 				prometheus.CounterValue, "rx_packets_total", "Count of packets received",
@@ -369,7 +415,7 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 			),
 			newJVMGCMetric(
 				prometheus.CounterValue, "collection_seconds_sum", "GC run time in seconds",
-				func(gc model.NodeJVMGCCollector) float64 { return float64(gc.CollectionTime / 1000) },
+				func(gc model.NodeJVMGCCollector) float64 { return float64(gc.CollectionTime) / 1000 },
 			),
 		},
 		breakerMetrics: []*breakerMetric{
@@ -425,6 +471,48 @@ func NewCollector(esClient elasticsearch.Client, exportMetricsForAllNodes bool)
 				"size_bytes", "Size of block device in bytes",
 				func(fs model.NodeFSData) float64 { return float64(fs.Total) },
 			),
+			newFSCounter(
+				"disk_reads_total", "Total number of reads from block device",
+				func(fs model.NodeFSData) float64 { return float64(fs.DiskReads) },
+			),
+			newFSCounter(
+				"disk_writes_total", "Total number of writes to block device",
+				func(fs model.NodeFSData) float64 { return float64(fs.DiskWrites) },
+			),
+			newFSCounter(
+				"disk_read_size_bytes_total", "Total size of reads from block device in bytes",
+				func(fs model.NodeFSData) float64 { return float64(fs.DiskReadSize) },
+			),
+			newFSCounter(
+				"disk_write_size_bytes_total", "Total size of writes to block device in bytes",
+				func(fs model.NodeFSData) float64 { return float64(fs.DiskWriteSize) },
+			),
+		},
+		ioDeviceMetrics: []*ioDeviceMetric{
+			newIODeviceCounter(
+				"operations_total", "Total number of read and write operations on block device",
+				func(d model.NodeFSIOStatsDevice) float64 { return float64(d.Operations) },
+			),
+			newIODeviceCounter(
+				"read_operations_total", "Total number of read operations on block device",
+				func(d model.NodeFSIOStatsDevice) float64 { return float64(d.ReadOperations) },
+			),
+			newIODeviceCounter(
+				"write_operations_total", "Total number of write operations on block device",
+				func(d model.NodeFSIOStatsDevice) float64 { return float64(d.WriteOperations) },
+			),
+			newIODeviceCounter(
+				"read_size_bytes_total", "Total size of data read from block device in bytes",
+				func(d model.NodeFSIOStatsDevice) float64 { return float64(d.ReadKilobytes * 1024) },
+			),
+			newIODeviceCounter(
+				"write_size_bytes_total", "Total size of data written to block device in bytes",
+				func(d model.NodeFSIOStatsDevice) float64 { return float64(d.WriteKilobytes * 1024) },
+			),
+			newIODeviceCounter(
+				"io_time_seconds_total", "Total time spent doing I/O on block device in seconds",
+				func(d model.NodeFSIOStatsDevice) float64 { return float64(d.IOTimeInMillis) / 1000 },
+			),
 		},
 	}
 }
@@ -446,72 +534,96 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	for _, metric := range c.filesystemMetrics {
 		ch <- metric.Desc()
 	}
+	for _, metric := range c.ioDeviceMetrics {
+		ch <- metric.Desc()
+	}
 }
 
 // Collect writes data to metrics channel
-func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
-	nodeStats, err := c.esClient.Nodes(c.exportMetricsForAllNodes)
-	if err != nil {
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	version := c.esClient.Version()
+
+	err := c.esClient.NodesStream(ctx, c.exportMetricsForAllNodes, statsFields, func(node model.Node) {
+		c.collectNode(clusterName, version, node, ch)
+	})
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Println("WARN: skipped fetching nodes stats: ", err)
+	} else if err != nil {
 		log.Println("ERROR: failed to fetch nodes stats: ", err)
-		return
+	}
+}
+
+// collectNode writes metrics of a node to metrics channel
+func (c *Collector) collectNode(clusterName string, version elasticsearch.Version, node model.Node, ch chan<- prometheus.Metric) {
+	for _, metric := range c.nodeMetrics {
+		if !metric.Versions.Contains(version) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			metric.Desc(),
+			metric.Type(),
+			metric.Value(node),
+			labelValuesNode(clusterName, node)...,
+		)
 	}
 
-	for _, node := range nodeStats.Nodes {
-		for _, metric := range c.nodeMetrics {
+	// GC Stats
+	for collector, gcStats := range node.JVM.GC.Collectors {
+		for _, metric := range c.gcCollectionMetrics {
 			ch <- prometheus.MustNewConstMetric(
 				metric.Desc(),
 				metric.Type(),
-				metric.Value(node),
-				labelValuesNode(clusterName, node)...,
+				metric.Value(gcStats),
+				append(labelValuesNode(clusterName, node), collector)...,
 			)
 		}
+	}
 
-		// GC Stats
-		for collector, gcStats := range node.JVM.GC.Collectors {
-			for _, metric := range c.gcCollectionMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc(),
-					metric.Type(),
-					metric.Value(gcStats),
-					append(labelValuesNode(clusterName, node), collector)...,
-				)
-			}
+	// Breaker stats
+	for breaker, bstats := range node.Breakers {
+		for _, metric := range c.breakerMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc(),
+				metric.Type(),
+				metric.Value(bstats),
+				append(labelValuesNode(clusterName, node), breaker)...,
+			)
 		}
+	}
 
-		// Breaker stats
-		for breaker, bstats := range node.Breakers {
-			for _, metric := range c.breakerMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc(),
-					metric.Type(),
-					metric.Value(bstats),
-					append(labelValuesNode(clusterName, node), breaker)...,
-				)
-			}
+	// Thread Pool stats
+	for pool, pstats := range node.ThreadPool {
+		for _, metric := range c.threadPoolMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc(),
+				metric.Type(),
+				metric.Value(pstats),
+				labelValuesThreadPool(clusterName, node, pool)...,
+			)
 		}
+	}
 
-		// Thread Pool stats
-		for pool, pstats := range node.ThreadPool {
-			for _, metric := range c.threadPoolMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc(),
-					metric.Type(),
-					metric.Value(pstats),
-					labelValuesThreadPool(clusterName, node, pool)...,
-				)
-			}
+	// File System Stats
+	for _, fsStats := range node.FS.Data {
+		for _, metric := range c.filesystemMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc(),
+				metric.Type(),
+				metric.Value(fsStats),
+				labelValuesFilesystem(clusterName, node, fsStats.Mount, fsStats.Path)...,
+			)
 		}
+	}
 
-		// File System Stats
-		for _, fsStats := range node.FS.Data {
-			for _, metric := range c.filesystemMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc(),
-					metric.Type(),
-					metric.Value(fsStats),
-					labelValuesFilesystem(clusterName, node, fsStats.Mount, fsStats.Path)...,
-				)
-			}
+	// File System I/O Stats (Linux only)
+	for _, deviceStats := range node.FS.IOStats.Devices {
+		for _, metric := range c.ioDeviceMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc(),
+				metric.Type(),
+				metric.Value(deviceStats),
+				labelValuesIODevice(clusterName, node, deviceStats.DeviceName)...,
+			)
 		}
 	}
 }
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"strconv"
+)
+
+// bucketSample is a value extracted from aggregation result with keys of enclosing buckets
+type bucketSample struct {
+	keys  []string
+	value float64
+}
+
+// extractBuckets walks nested bucket aggregations and returns values of the innermost buckets.
+// Value is either "doc_count" of the bucket or a "value" of single-value metric sub-aggregation.
+// Without bucket aggregations value is taken from the top-level metric aggregation.
+func extractBuckets(aggs map[string]interface{}, buckets []string, value string) []bucketSample {
+	return walkBuckets(aggs, buckets, value, nil)
+}
+
+func walkBuckets(container map[string]interface{}, buckets []string, value string, keys []string) []bucketSample {
+	if len(buckets) == 0 {
+		v, ok := bucketValue(container, value)
+		if !ok {
+			return nil
+		}
+		return []bucketSample{{keys: keys, value: v}}
+	}
+
+	agg, ok := container[buckets[0]].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var samples []bucketSample
+	visit := func(key string, bucket interface{}) {
+		b, ok := bucket.(map[string]interface{})
+		if !ok {
+			return
+		}
+		nestedKeys := append(append([]string{}, keys...), key)
+		samples = append(samples, walkBuckets(b, buckets[1:], value, nestedKeys)...)
+	}
+
+	switch bs := agg["buckets"].(type) {
+	case []interface{}:
+		for _, bucket := range bs {
+			visit(bucketKey(bucket), bucket)
+		}
+	case map[string]interface{}:
+		// keyed buckets, e.g. filters aggregation
+		for key, bucket := range bs {
+			visit(key, bucket)
+		}
+	}
+
+	return samples
+}
+
+func bucketValue(bucket map[string]interface{}, value string) (float64, bool) {
+	if value == "doc_count" {
+		v, ok := bucket["doc_count"].(float64)
+		return v, ok
+	}
+
+	agg, ok := bucket[value].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	// metric aggregations return null value when there are no documents
+	v, ok := agg["value"].(float64)
+	return v, ok
+}
+
+func bucketKey(bucket interface{}) string {
+	b, ok := bucket.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	if key, ok := b["key_as_string"].(string); ok {
+		return key
+	}
+
+	switch key := b["key"].(type) {
+	case string:
+		return key
+	case float64:
+		return strconv.FormatFloat(key, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(key)
+	}
+
+	return ""
+}
@@ -0,0 +1,87 @@
+package query
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const aggregationsBody = `
+{
+	"by_service": {
+		"buckets": [
+			{
+				"key": "api",
+				"doc_count": 30,
+				"by_level": {"buckets": {"error": {"doc_count": 3, "latency": {"value": 1.5}}, "warn": {"doc_count": 27, "latency": {"value": null}}}}
+			},
+			{
+				"key": 404,
+				"doc_count": 12,
+				"by_level": {"buckets": {"error": {"doc_count": 12, "latency": {"value": 0.5}}}}
+			}
+		]
+	},
+	"max_latency": {"value": 2.5},
+	"per_day": {
+		"buckets": [{"key": 1584230400000, "key_as_string": "2020-03-15", "doc_count": 7}]
+	}
+}`
+
+func TestExtractBuckets(t *testing.T) {
+	var aggs map[string]interface{}
+	if err := json.Unmarshal([]byte(aggregationsBody), &aggs); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		buckets []string
+		value   string
+		want    []bucketSample
+	}{
+		{
+			buckets: []string{"by_service"},
+			value:   "doc_count",
+			want:    []bucketSample{{keys: []string{"404"}, value: 12}, {keys: []string{"api"}, value: 30}},
+		},
+		{
+			buckets: []string{"by_service", "by_level"},
+			value:   "doc_count",
+			want: []bucketSample{
+				{keys: []string{"404", "error"}, value: 12},
+				{keys: []string{"api", "error"}, value: 3},
+				{keys: []string{"api", "warn"}, value: 27},
+			},
+		},
+		{
+			buckets: []string{"by_service", "by_level"},
+			value:   "latency",
+			want:    []bucketSample{{keys: []string{"404", "error"}, value: 0.5}, {keys: []string{"api", "error"}, value: 1.5}},
+		},
+		{
+			value: "max_latency",
+			want:  []bucketSample{{value: 2.5}},
+		},
+		{
+			buckets: []string{"per_day"},
+			value:   "doc_count",
+			want:    []bucketSample{{keys: []string{"2020-03-15"}, value: 7}},
+		},
+		{
+			buckets: []string{"unknown"},
+			value:   "doc_count",
+		},
+	}
+
+	for _, test := range tests {
+		got := extractBuckets(aggs, test.buckets, test.value)
+		sort.Slice(got, func(i, j int) bool {
+			return strings.Join(got[i].keys, ",") < strings.Join(got[j].keys, ",")
+		})
+		if !reflect.DeepEqual(test.want, got) {
+			t.Fatalf("Unexpected samples for %v/%s: want %+v, got %+v", test.buckets, test.value, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	typeSearch = "search"
+	typeCount  = "count"
+)
+
+var (
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRe  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Config is a declaration of ES search or count request exported as metrics
+type Config struct {
+	// Name identifies query in logs
+	Name string `json:"name"`
+	// Index is an index name or multi-target expression, e.g. "logs-*"
+	Index string `json:"index"`
+	// Type is "search" (default) or "count"
+	Type string `json:"type"`
+	// Body is a query DSL, relative time placeholders like "{{now-5m}}" are replaced on each request
+	Body json.RawMessage `json:"body"`
+	// Timeout is a request timeout. Empty means the ES client timeout
+	Timeout string `json:"timeout"`
+	// Interval is a minimal time between requests, cached values are exported in between. Empty means every scrape
+	Interval     string              `json:"interval"`
+	Hits         *HitsConfig         `json:"hits"`
+	Aggregations []AggregationConfig `json:"aggregations"`
+}
+
+// HitsConfig declares a metric with total number of matched documents
+type HitsConfig struct {
+	// Name is a metric name without "elasticsearch_" prefix
+	Name string `json:"name"`
+	Help string `json:"help"`
+}
+
+// AggregationConfig declares a metric with values of aggregation buckets
+type AggregationConfig struct {
+	// Name is a metric name without "elasticsearch_" prefix
+	Name string `json:"name"`
+	Help string `json:"help"`
+	// Buckets are names of nested bucket aggregations, the outermost first
+	Buckets []string `json:"buckets"`
+	// Labels are label names for keys of the corresponding bucket aggregations
+	Labels []string `json:"labels"`
+	// Value is "doc_count" (default) or a name of single-value metric sub-aggregation
+	Value string `json:"value"`
+}
+
+// trackTotalHitsVersions are versions supporting "track_total_hits" search parameter.
+// ES 7.0+ counts hits up to 10000 by default, so exact total is requested explicitly
+var trackTotalHitsVersions = elasticsearch.Since("6.0")
+
+// Query is a compiled query
+type Query struct {
+	name     string
+	index    string
+	_type    string
+	body     string
+	timeout  time.Duration
+	interval time.Duration
+
+	hits         *metrics.Metric
+	aggregations []*aggregation
+
+	mu        sync.Mutex
+	samples   []sample
+	updatedAt time.Time
+}
+
+type aggregation struct {
+	*metrics.Metric
+	config AggregationConfig
+}
+
+type sample struct {
+	metric      *metrics.Metric
+	labelValues []string
+	value       float64
+}
+
+// NewQueries validates configs and returns compiled queries. Metric names must be unique across queries
+// and must not be in reserved names, e.g. names of built-in metrics
+func NewQueries(configs []Config, reserved map[string]bool) ([]*Query, error) {
+	result := make([]*Query, 0, len(configs))
+	names := make(map[string]bool)
+	for _, config := range configs {
+		q, err := newQuery(config)
+		if err != nil {
+			return nil, fmt.Errorf("query %q: %s", config.Name, err)
+		}
+
+		for _, m := range q.metrics() {
+			name := metrics.DescName(m.Desc())
+			if names[name] {
+				return nil, fmt.Errorf("query %q: duplicate metric name %s", config.Name, name)
+			}
+			if reserved[name] {
+				return nil, fmt.Errorf("query %q: metric %s conflicts with built-in metric", config.Name, name)
+			}
+			names[name] = true
+		}
+
+		result = append(result, q)
+	}
+
+	return result, nil
+}
+
+func newQuery(config Config) (*Query, error) {
+	if config.Index == "" || strings.ContainsAny(config.Index, "/?#") {
+		return nil, fmt.Errorf("invalid index %q", config.Index)
+	}
+
+	switch config.Type {
+	case "":
+		config.Type = typeSearch
+	case typeSearch:
+	case typeCount:
+		if len(config.Aggregations) > 0 {
+			return nil, fmt.Errorf("aggregations are not supported by count query")
+		}
+	default:
+		return nil, fmt.Errorf("unknown query type %q", config.Type)
+	}
+
+	if config.Hits == nil && len(config.Aggregations) == 0 {
+		return nil, fmt.Errorf("either hits or aggregations metric must be declared")
+	}
+
+	body := string(config.Body)
+	if len(config.Body) > 0 {
+		rendered, err := renderBody(body, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid([]byte(rendered)) {
+			return nil, fmt.Errorf("query body is not a valid JSON after placeholders substitution")
+		}
+	}
+
+	q := &Query{
+		name:  config.Name,
+		index: config.Index,
+		_type: config.Type,
+		body:  body,
+	}
+
+	var err error
+	if config.Timeout != "" {
+		if q.timeout, err = time.ParseDuration(config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	if config.Interval != "" {
+		if q.interval, err = time.ParseDuration(config.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Hits != nil {
+		if err := validateMetricName(config.Hits.Name); err != nil {
+			return nil, err
+		}
+		q.hits = metrics.New(prometheus.GaugeValue, "", config.Hits.Name, config.Hits.Help, []string{"cluster"})
+	}
+
+	for _, agg := range config.Aggregations {
+		if err := validateMetricName(agg.Name); err != nil {
+			return nil, err
+		}
+		if len(agg.Labels) != len(agg.Buckets) {
+			return nil, fmt.Errorf("metric %q must have a label for each bucket aggregation", agg.Name)
+		}
+		if agg.Value == "" {
+			agg.Value = "doc_count"
+		}
+		if len(agg.Buckets) == 0 && agg.Value == "doc_count" {
+			return nil, fmt.Errorf("metric %q must have either buckets or a metric aggregation value", agg.Name)
+		}
+
+		labelNames := []string{"cluster"}
+		for _, label := range agg.Labels {
+			if !labelNameRe.MatchString(label) || label == "cluster" {
+				return nil, fmt.Errorf("invalid label name %q", label)
+			}
+			labelNames = append(labelNames, label)
+		}
+
+		q.aggregations = append(q.aggregations, &aggregation{
+			Metric: metrics.New(prometheus.GaugeValue, "", agg.Name, agg.Help, labelNames),
+			config: agg,
+		})
+	}
+
+	return q, nil
+}
+
+func validateMetricName(name string) error {
+	if !metricNameRe.MatchString(name) {
+		return fmt.Errorf("invalid metric name %q", name)
+	}
+	if strings.HasPrefix(name, "exporter_") {
+		return fmt.Errorf("metric %q: metric name prefix \"exporter_\" is reserved for metrics of exporter itself", name)
+	}
+	return nil
+}
+
+// metrics returns metrics of hits and aggregations declared by the query
+func (q *Query) metrics() []*metrics.Metric {
+	var result []*metrics.Metric
+	if q.hits != nil {
+		result = append(result, q.hits)
+	}
+	for _, agg := range q.aggregations {
+		result = append(result, agg.Metric)
+	}
+	return result
+}
+
+// Collector is a collector of query-based metrics declared in configuration file
+type Collector struct {
+	esClient elasticsearch.Client
+	queries  []*Query
+}
+
+// NewCollector returns new query-based metrics collector
+func NewCollector(esClient elasticsearch.Client, queries []*Query) *Collector {
+	return &Collector{
+		esClient: esClient,
+		queries:  queries,
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, q := range c.queries {
+		for _, m := range q.metrics() {
+			ch <- m.Desc()
+		}
+	}
+}
+
+// Collect writes data to metrics channel.
+// Queries run concurrently, so a slow query doesn't delay the others.
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	wg := sync.WaitGroup{}
+	for _, q := range c.queries {
+		wg.Add(1)
+		go func(q *Query) {
+			defer wg.Done()
+
+			for _, s := range q.refresh(ctx, c.esClient) {
+				ch <- prometheus.MustNewConstMetric(
+					s.metric.Desc(),
+					s.metric.Type(),
+					s.value,
+					append([]string{clusterName}, s.labelValues...)...,
+				)
+			}
+		}(q)
+	}
+	wg.Wait()
+}
+
+// refresh returns cached samples or runs the query if interval has passed.
+// Last known samples are returned on request failure.
+func (q *Query) refresh(ctx context.Context, esClient elasticsearch.Client) []sample {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.updatedAt.IsZero() && time.Since(q.updatedAt) < q.interval {
+		return q.samples
+	}
+
+	samples, err := q.run(ctx, esClient)
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Printf("WARN: skipped running query %s: %s", q.name, err)
+		return q.samples
+	}
+	if err != nil {
+		log.Printf("ERROR: failed to run query %s: %s", q.name, err)
+		return q.samples
+	}
+
+	q.samples = samples
+	q.updatedAt = time.Now()
+
+	return q.samples
+}
+
+func (q *Query) run(ctx context.Context, esClient elasticsearch.Client) ([]sample, error) {
+	if q.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.timeout)
+		defer cancel()
+	}
+
+	rendered := "{}"
+	if q.body != "" {
+		var err error
+		if rendered, err = renderBody(q.body, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	body := strings.NewReader(rendered)
+
+	if q._type == typeCount {
+		resp, err := esClient.Count(ctx, q.index, body)
+		if err != nil {
+			return nil, err
+		}
+		return []sample{{metric: q.hits, value: float64(resp.Count)}}, nil
+	}
+
+	if q.hits != nil && trackTotalHitsVersions.Contains(esClient.Version()) {
+		var err error
+		if rendered, err = withTrackTotalHits(rendered); err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(rendered)
+	}
+
+	resp, err := esClient.Search(ctx, q.index, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.TimedOut {
+		return nil, fmt.Errorf("search timed out")
+	}
+
+	var samples []sample
+	if q.hits != nil {
+		// a lower bound of total hits isn't exported as an exact value
+		if resp.Hits.Total.Relation == "gte" {
+			log.Printf("ERROR: query %s hits total is a lower bound %d, increase \"track_total_hits\" of the query", q.name, resp.Hits.Total.Value)
+		} else {
+			samples = append(samples, sample{metric: q.hits, value: float64(resp.Hits.Total.Value)})
+		}
+	}
+
+	for _, agg := range q.aggregations {
+		// the same label values can't be exported twice, so only the first one is kept
+		seen := make(map[string]bool)
+		for _, b := range extractBuckets(resp.Aggregations, agg.config.Buckets, agg.config.Value) {
+			key := strings.Join(b.keys, "\xff")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			samples = append(samples, sample{metric: agg.Metric, labelValues: b.keys, value: b.value})
+		}
+	}
+
+	return samples, nil
+}
+
+// withTrackTotalHits adds "track_total_hits": true to search body unless it's already set by the query
+func withTrackTotalHits(body string) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return "", fmt.Errorf("query body is not a JSON object: %s", err)
+	}
+	if _, ok := fields["track_total_hits"]; ok {
+		return body, nil
+	}
+	if fields == nil {
+		fields = make(map[string]json.RawMessage)
+	}
+	fields["track_total_hits"] = json.RawMessage("true")
+
+	result, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
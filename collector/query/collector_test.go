@@ -0,0 +1,126 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/testdata"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+)
+
+// newESClient returns client responding to searches with given body and passing search request bodies to check
+func newESClient(response string, check func(body map[string]interface{})) elasticsearch.Client {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.InfoBody)
+	mockHTTPClient.Post("/logs-*/_search").WithChecker(func(r *http.Request) bool {
+		var body map[string]interface{}
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		check(body)
+		return true
+	}).WillReturn(200, response)
+
+	return elasticsearch.NewClient(mockHTTPClient)
+}
+
+func TestQuery_Run_TrackTotalHits(t *testing.T) {
+	cases := []struct {
+		body      string
+		response  string
+		trackHits interface{}
+		hits      []float64
+	}{
+		{
+			body:      `{"query": {"match_all": {}}}`,
+			response:  `{"hits": {"total": {"value": 25000, "relation": "eq"}}}`,
+			trackHits: true,
+			hits:      []float64{25000},
+		},
+		{
+			// limit set by the query is kept, and a lower bound of hits isn't exported
+			body:      `{"query": {"match_all": {}}, "track_total_hits": 100}`,
+			response:  `{"hits": {"total": {"value": 100, "relation": "gte"}}}`,
+			trackHits: 100.0,
+			hits:      nil,
+		},
+	}
+
+	for _, tt := range cases {
+		queries, err := NewQueries([]Config{{
+			Name:  "errors",
+			Index: "logs-*",
+			Body:  json.RawMessage(tt.body),
+			Hits:  &HitsConfig{Name: "errors_total"},
+		}}, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		var trackHits interface{}
+		esClient := newESClient(tt.response, func(body map[string]interface{}) {
+			trackHits = body["track_total_hits"]
+		})
+
+		samples, err := queries[0].run(context.Background(), esClient)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if trackHits != tt.trackHits {
+			t.Fatalf("Unexpected track_total_hits of %s: want %v, got %v", tt.body, tt.trackHits, trackHits)
+		}
+
+		var hits []float64
+		for _, s := range samples {
+			hits = append(hits, s.value)
+		}
+		if len(hits) != len(tt.hits) || (len(hits) > 0 && hits[0] != tt.hits[0]) {
+			t.Fatalf("Unexpected hits samples of %s: want %v, got %v", tt.body, tt.hits, hits)
+		}
+	}
+}
+
+func TestNewQueries_Conflicts(t *testing.T) {
+	query := Config{
+		Name:         "errors",
+		Index:        "logs-*",
+		Hits:         &HitsConfig{Name: "errors_total"},
+		Aggregations: []AggregationConfig{{Name: "errors_by_level", Buckets: []string{"levels"}, Labels: []string{"level"}}},
+	}
+	other := query
+	other.Name = "other"
+	other.Aggregations = nil
+	other.Hits = &HitsConfig{Name: "errors_by_level"}
+	sameMetric := query
+	sameMetric.Hits = &HitsConfig{Name: "errors_by_level"}
+	exporter := other
+	exporter.Hits = &HitsConfig{Name: "exporter_errors_total"}
+
+	cases := []struct {
+		configs  []Config
+		reserved map[string]bool
+		err      string
+	}{
+		{[]Config{query, other}, nil, `query "other": duplicate metric name elasticsearch_errors_by_level`},
+		{[]Config{sameMetric}, nil, `query "errors": duplicate metric name elasticsearch_errors_by_level`},
+		{
+			[]Config{query},
+			map[string]bool{"elasticsearch_errors_total": true},
+			`query "errors": metric elasticsearch_errors_total conflicts with built-in metric`,
+		},
+		{[]Config{exporter}, nil, `query "other": metric "exporter_errors_total": metric name prefix "exporter_" is reserved for metrics of exporter itself`},
+	}
+
+	for _, c := range cases {
+		if _, err := NewQueries(c.configs, c.reserved); err == nil || err.Error() != c.err {
+			t.Fatalf("Unexpected error: want %q, got %v", c.err, err)
+		}
+	}
+
+	if _, err := NewQueries([]Config{query}, map[string]bool{"elasticsearch_index_primaries_docs_count": true}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,73 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// placeholderRe matches relative time placeholders like "{{now}}", "{{now-5m}}" or "{{now-1d|epoch_millis}}"
+var placeholderRe = regexp.MustCompile(`\{\{\s*now\s*(?:([+-])\s*([0-9a-z.]+))?\s*(?:\|\s*([a-z_]+)\s*)?\}\}`)
+
+// renderBody replaces relative time placeholders in query body with absolute time values
+func renderBody(body string, now time.Time) (string, error) {
+	var err error
+	rendered := placeholderRe.ReplaceAllStringFunc(body, func(placeholder string) string {
+		value, e := renderPlaceholder(placeholderRe.FindStringSubmatch(placeholder), now)
+		if e != nil && err == nil {
+			err = fmt.Errorf("placeholder %s: %s", placeholder, e)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if strings.Contains(rendered, "{{") {
+		return "", fmt.Errorf("unknown placeholder in query body")
+	}
+
+	return rendered, nil
+}
+
+func renderPlaceholder(groups []string, now time.Time) (string, error) {
+	sign, offset, format := groups[1], groups[2], groups[3]
+
+	t := now.UTC()
+	if offset != "" {
+		d, err := parseDuration(offset)
+		if err != nil {
+			return "", err
+		}
+		if sign == "-" {
+			d = -d
+		}
+		t = t.Add(d)
+	}
+
+	switch format {
+	case "":
+		return t.Format("2006-01-02T15:04:05.000Z07:00"), nil
+	case "epoch_millis":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10), nil
+	case "epoch_second":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+
+	return "", fmt.Errorf("unknown format %q", format)
+}
+
+// parseDuration parses Go duration extended with days, e.g. "7d"
+func parseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
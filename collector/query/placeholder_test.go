@@ -0,0 +1,45 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderBody(t *testing.T) {
+	now := time.Date(2020, 3, 15, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"gte": "{{now}}"}`, `{"gte": "2020-03-15T12:30:00.000Z"}`},
+		{`{"gte": "{{now-5m}}"}`, `{"gte": "2020-03-15T12:25:00.000Z"}`},
+		{`{"gte": "{{ now - 1d }}"}`, `{"gte": "2020-03-14T12:30:00.000Z"}`},
+		{`{"lt": "{{now+1h30m}}"}`, `{"lt": "2020-03-15T14:00:00.000Z"}`},
+		{`{"gte": {{now-1s|epoch_millis}}}`, `{"gte": 1584275399000}`},
+		{`{"gte": {{now|epoch_second}}}`, `{"gte": 1584275400}`},
+		{`{"query": {"match_all": {}}}`, `{"query": {"match_all": {}}}`},
+	}
+
+	for _, test := range tests {
+		got, err := renderBody(test.body, now)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", test.body, err)
+		}
+		if got != test.want {
+			t.Fatalf("Unexpected result for %s: want %s, got %s", test.body, test.want, got)
+		}
+	}
+}
+
+func TestRenderBody_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{"gte": "{{now-5x}}"}`,
+		`{"gte": "{{now|unix}}"}`,
+		`{"gte": "{{yesterday}}"}`,
+	} {
+		if _, err := renderBody(body, time.Now()); err == nil {
+			t.Fatalf("Expected error for %s", body)
+		}
+	}
+}
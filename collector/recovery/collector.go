@@ -1,15 +1,22 @@
 package recovery
 
 import (
+	"context"
+	"errors"
 	"log"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+const stageDone = "DONE"
+
 // Recovery stages mapping
 var recoveryStages = map[string]float64{
 	"INIT":     1,
@@ -20,11 +27,22 @@ var recoveryStages = map[string]float64{
 	"DONE":     6,
 }
 
+// DefaultDurationBuckets are default buckets of completed recovery duration histogram in seconds
+var DefaultDurationBuckets = []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600, 7200, 21600}
+
 type recoveryMetric struct {
 	*metrics.Metric
 
 	Value       func(model.ShardRecovery) float64
 	LabelValues func(cluster, index string, shard model.ShardRecovery) []string
+	// Exported checks if metric is defined for the shard recovery
+	Exported func(model.ShardRecovery) bool
+}
+
+// onlyIf makes metric exported only for shard recoveries passing the check
+func (m *recoveryMetric) onlyIf(check func(model.ShardRecovery) bool) *recoveryMetric {
+	m.Exported = check
+	return m
 }
 
 func newMetric(t prometheus.ValueType, name, help string, valueExtractor func(model.ShardRecovery) float64) *recoveryMetric {
@@ -41,7 +59,25 @@ func newMetric(t prometheus.ValueType, name, help string, valueExtractor func(mo
 		LabelValues: func(cluster, index string, shard model.ShardRecovery) []string {
 			return []string{cluster, index, strconv.Itoa(int(shard.ID)), shard.Source.Name, shard.Target.Name}
 		},
+		Exported: func(model.ShardRecovery) bool { return true },
+	}
+}
+
+// throughput returns average speed of index files recovery in bytes per second
+func throughput(s model.ShardRecovery) float64 {
+	if s.Index.TotalTimeInMillis <= 0 {
+		return 0
+	}
+	return float64(s.Index.Size.RecoveredInBytes) / (float64(s.Index.TotalTimeInMillis) / 1000)
+}
+
+// remainingBytes returns size of index files which are still to be recovered
+func remainingBytes(s model.ShardRecovery) float64 {
+	remaining := s.Index.Size.TotalInBytes - s.Index.Size.ReusedInBytes - s.Index.Size.RecoveredInBytes
+	if remaining < 0 {
+		return 0
 	}
+	return float64(remaining)
 }
 
 var recoveryShardInfo = metrics.NewDesc(
@@ -52,18 +88,35 @@ var recoveryShardInfo = metrics.NewDesc(
 	nil,
 )
 
-// Collector is a metrics collection for ElasticSearch indices aliases
+// Collector is a metrics collection for ElasticSearch shards recovery
 type Collector struct {
 	esClient elasticsearch.Client
 
-	recoveryInfo prometheus.Metric
-	metrics      []*recoveryMetric
+	metrics []*recoveryMetric
+
+	// trackCompleted enables tracking of recoveries across scrapes
+	trackCompleted    bool
+	completedDuration *prometheus.HistogramVec
+
+	// mu guards completed recoveries state shared between scrapes
+	mu sync.Mutex
+	// completed are keys of completed recoveries which have already been observed, nil before the first scrape
+	completed map[string]bool
 }
 
-// NewCollector returns new metrics collection for indices aliases
-func NewCollector(esClient elasticsearch.Client) *Collector {
+// NewCollector returns new metrics collection for shards recovery.
+// If trackCompleted is true, completed recoveries are requested too
+// and their durations are observed once per recovery.
+func NewCollector(esClient elasticsearch.Client, trackCompleted bool) *Collector {
 	return &Collector{
-		esClient: esClient,
+		esClient:       esClient,
+		trackCompleted: trackCompleted,
+		completedDuration: metrics.NewHistogramVec(
+			"index_recovery", "completed_duration_seconds",
+			"Duration of completed shard recoveries in seconds",
+			DefaultDurationBuckets,
+			[]string{"cluster", "type"},
+		),
 		metrics: []*recoveryMetric{
 			newMetric(
 				prometheus.GaugeValue, "bytes_total", "Total size of index shard in bytes",
@@ -101,6 +154,50 @@ func NewCollector(esClient elasticsearch.Client) *Collector {
 					return float64(s.Translog.Recovered)
 				},
 			),
+			newMetric(
+				prometheus.GaugeValue, "bytes_reused", "Size of data reused from target node in bytes",
+				func(s model.ShardRecovery) float64 {
+					return float64(s.Index.Size.ReusedInBytes)
+				},
+			),
+			newMetric(
+				prometheus.GaugeValue, "files_reused", "Number of files reused from target node",
+				func(s model.ShardRecovery) float64 {
+					return float64(s.Index.Files.Reused)
+				},
+			),
+			newMetric(
+				prometheus.GaugeValue, "duration_seconds", "Time elapsed since the start of recovery in seconds",
+				func(s model.ShardRecovery) float64 {
+					return float64(s.TotalTimeInMillis) / 1000
+				},
+			),
+			newMetric(
+				prometheus.GaugeValue, "source_throttle_seconds", "Time recovery was throttled on source node in seconds",
+				func(s model.ShardRecovery) float64 {
+					return float64(s.Index.SourceThrottleTimeInMillis) / 1000
+				},
+			),
+			newMetric(
+				prometheus.GaugeValue, "target_throttle_seconds", "Time recovery was throttled on target node in seconds",
+				func(s model.ShardRecovery) float64 {
+					return float64(s.Index.TargetThrottleTimeInMillis) / 1000
+				},
+			),
+			newMetric(
+				prometheus.GaugeValue, "throughput_bytes_per_second", "Average speed of index files recovery in bytes per second",
+				throughput,
+			).onlyIf(func(s model.ShardRecovery) bool {
+				return s.Index.TotalTimeInMillis > 0
+			}),
+			newMetric(
+				prometheus.GaugeValue, "eta_seconds", "Estimated time until index files are recovered in seconds, based on average throughput",
+				func(s model.ShardRecovery) float64 {
+					return remainingBytes(s) / throughput(s)
+				},
+			).onlyIf(func(s model.ShardRecovery) bool {
+				return throughput(s) > 0
+			}),
 			newMetric(
 				prometheus.GaugeValue, "stage", "Index shard recovery stage. 1 = INIT, 2 = INDEX, 3 = START, 4 = TRANSLOG, 5 = FINALIZE, 6 = DONE.",
 				func(s model.ShardRecovery) float64 {
@@ -116,21 +213,43 @@ func NewCollector(esClient elasticsearch.Client) *Collector {
 
 // Describe implements prometheus.Collector interface
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- recoveryShardInfo
 	for _, metric := range c.metrics {
 		ch <- metric.Desc()
 	}
+	if c.trackCompleted {
+		c.completedDuration.Describe(ch)
+	}
 }
 
 // Collect writes data to metrics channel
-func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
-	indicesRecovery, err := c.esClient.Recovery()
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	if c.trackCompleted {
+		// histogram is exported even if request fails, so its series don't disappear
+		defer c.completedDuration.Collect(ch)
+	}
+
+	indicesRecovery, err := c.esClient.Recovery(ctx, !c.trackCompleted)
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Println("WARN: skipped fetching recovery stats: ", err)
+		return
+	}
 	if err != nil {
 		log.Println("ERROR: failed to fetch recovery stats: ", err)
 		return
 	}
 
+	if c.trackCompleted {
+		c.observeCompleted(clusterName, indicesRecovery)
+	}
+
 	for indexName, index := range indicesRecovery {
 		for _, shard := range index.Shards {
+			if shard.Stage == stageDone {
+				// completed recoveries are requested only for tracking
+				continue
+			}
+
 			isPrimary := "false"
 			if shard.Primary {
 				isPrimary = "true"
@@ -150,6 +269,10 @@ func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
 			}
 
 			for _, metric := range c.metrics {
+				if !metric.Exported(shard) {
+					continue
+				}
+
 				m, err := prometheus.NewConstMetric(
 					metric.Desc(),
 					metric.Type(),
@@ -167,3 +290,35 @@ func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
 		}
 	}
 }
+
+// observeCompleted observes durations of recoveries completed since the previous scrape.
+// Recoveries completed before the first scrape are not observed.
+func (c *Collector) observeCompleted(clusterName string, indicesRecovery model.Recovery) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	firstScrape := c.completed == nil
+	completed := make(map[string]bool)
+
+	for indexName, index := range indicesRecovery {
+		for _, shard := range index.Shards {
+			if shard.Stage != stageDone {
+				continue
+			}
+
+			key := strings.Join([]string{
+				indexName, strconv.Itoa(int(shard.ID)), shard.Target.ID, strconv.FormatInt(shard.StartTimeInMillis, 10),
+			}, "|")
+			completed[key] = true
+
+			if !firstScrape && !c.completed[key] {
+				c.completedDuration.
+					WithLabelValues(clusterName, strings.ToLower(shard.Type)).
+					Observe(float64(shard.TotalTimeInMillis) / 1000)
+			}
+		}
+	}
+
+	// recoveries which are not reported anymore are forgotten
+	c.completed = completed
+}
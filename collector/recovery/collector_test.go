@@ -0,0 +1,61 @@
+package recovery
+
+import (
+	"testing"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func completedRecovery(id, startTime, totalTime int64) model.ShardRecovery {
+	return model.ShardRecovery{
+		ID:                id,
+		Type:              "PEER",
+		Stage:             stageDone,
+		StartTimeInMillis: startTime,
+		TotalTimeInMillis: totalTime,
+		Target:            model.RecoveryDestination{ID: "a3ZUS9r2RHyn0b4UeTSX0g"},
+	}
+}
+
+func TestCollector_ObserveCompleted(t *testing.T) {
+	c := NewCollector(nil, true)
+
+	// recoveries completed before the first scrape are not observed
+	c.observeCompleted("test-cluster", model.Recovery{
+		"twitter": {Shards: []model.ShardRecovery{completedRecovery(0, 1000, 5000)}},
+	})
+	// the same recovery is reported again along with a new one
+	c.observeCompleted("test-cluster", model.Recovery{
+		"twitter": {Shards: []model.ShardRecovery{completedRecovery(0, 1000, 5000), completedRecovery(1, 2000, 90000)}},
+	})
+	c.observeCompleted("test-cluster", model.Recovery{
+		"twitter": {Shards: []model.ShardRecovery{completedRecovery(1, 2000, 90000)}},
+	})
+
+	var metric dto.Metric
+	if err := c.completedDuration.WithLabelValues("test-cluster", "peer").(prometheus.Histogram).Write(&metric); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if metric.Histogram.GetSampleCount() != 1 || metric.Histogram.GetSampleSum() != 90 {
+		t.Fatalf("Unexpected histogram count and sum: %d, %v", metric.Histogram.GetSampleCount(), metric.Histogram.GetSampleSum())
+	}
+}
+
+func TestThroughputAndRemainingBytes(t *testing.T) {
+	s := model.ShardRecovery{
+		Index: model.IndexRecoveryState{
+			Size:              model.IndexRecoverySize{TotalInBytes: 1000, ReusedInBytes: 200, RecoveredInBytes: 300},
+			TotalTimeInMillis: 2000,
+		},
+	}
+
+	if got := throughput(s); got != 150 {
+		t.Fatalf("Unexpected throughput: want 150, got %v", got)
+	}
+	if got := remainingBytes(s); got != 500 {
+		t.Fatalf("Unexpected remaining bytes: want 500, got %v", got)
+	}
+}
@@ -0,0 +1,114 @@
+package segmentreplication
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var labelsShard = []string{"cluster", "index", "shard", "node"}
+
+type shardMetric struct {
+	*metrics.Metric
+	// Value returns raw cat API value and its multiplier to base unit
+	Value func(shard model.SegmentReplicationShard) (string, float64)
+}
+
+// Collector is a metrics collector for OpenSearch segment replication lag of replica shards
+type Collector struct {
+	esClient elasticsearch.Client
+
+	shardMetrics []*shardMetric
+}
+
+// NewCollector returns new metrics collector for segment replication
+func NewCollector(esClient elasticsearch.Client) *Collector {
+	return &Collector{
+		esClient: esClient,
+		shardMetrics: []*shardMetric{
+			{
+				Metric: metrics.New(
+					prometheus.GaugeValue, "segment_replication", "checkpoints_behind",
+					"Number of checkpoints replica shard is behind primary", labelsShard,
+				),
+				Value: func(s model.SegmentReplicationShard) (string, float64) { return s.CheckpointsBehind, 1 },
+			},
+			{
+				Metric: metrics.New(
+					prometheus.GaugeValue, "segment_replication", "bytes_behind",
+					"Size of segments replica shard is behind primary in bytes", labelsShard,
+				),
+				Value: func(s model.SegmentReplicationShard) (string, float64) { return s.BytesBehind, 1 },
+			},
+			{
+				Metric: metrics.New(
+					prometheus.GaugeValue, "segment_replication", "current_lag_seconds",
+					"Time of ongoing replication event of replica shard in seconds", labelsShard,
+				),
+				Value: func(s model.SegmentReplicationShard) (string, float64) { return s.CurrentLag, 0.001 },
+			},
+			{
+				Metric: metrics.New(
+					prometheus.GaugeValue, "segment_replication", "last_completed_lag_seconds",
+					"Time of the last completed replication event of replica shard in seconds", labelsShard,
+				),
+				Value: func(s model.SegmentReplicationShard) (string, float64) { return s.LastCompletedLag, 0.001 },
+			},
+			{
+				Metric: metrics.New(
+					prometheus.CounterValue, "segment_replication", "rejected_requests_total",
+					"Total replication requests rejected for replica shard because it fell too far behind", labelsShard,
+				),
+				Value: func(s model.SegmentReplicationShard) (string, float64) { return s.RejectedRequests, 1 },
+			},
+		},
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range c.shardMetrics {
+		ch <- metric.Desc()
+	}
+}
+
+// Collect writes data to metrics channel
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	shards, err := c.esClient.SegmentReplication(ctx)
+	if errors.Is(err, elasticsearch.ErrNotSupported) {
+		return
+	}
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Println("WARN: skipped fetching segment replication state: ", err)
+		return
+	}
+	if err != nil {
+		log.Println("ERROR: failed to fetch segment replication state: ", err)
+		return
+	}
+
+	for _, shard := range shards {
+		index, shardNumber := shard.Shard()
+		for _, metric := range c.shardMetrics {
+			raw, multiplier := metric.Value(shard)
+			// columns missing in older versions are empty
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc(),
+				metric.Type(),
+				value*multiplier,
+				clusterName, index, shardNumber, shard.TargetNode,
+			)
+		}
+	}
+}
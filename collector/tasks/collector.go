@@ -1,20 +1,62 @@
 package tasks
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
-	"strings"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// maxDescriptionLength limits length of task description label, search descriptions may contain whole queries
+const maxDescriptionLength = 256
+
+// DefaultDurationBuckets are default buckets of task duration histogram in seconds
+var DefaultDurationBuckets = []float64{0.1, 1, 5, 10, 30, 60, 300, 900, 1800, 3600, 7200}
+
 // Labels lists for different kind of metrics
 var (
-	labelsTasks = []string{"action", "node", "cluster"}
+	labelsTasks        = []string{"action", "node", "cluster"}
+	labelsTaskActions  = []string{"action", "cluster"}
+	labelsLongestTasks = []string{"action", "node", "cluster", "task_id", "description"}
 )
 
+// Config is a tasks collector configuration
+type Config struct {
+	// TopN is a number of the longest running tasks to export descriptions for, disabled if zero
+	TopN int
+	// DurationBuckets are upper bounds of task duration histogram buckets in seconds
+	DurationBuckets []float64
+	// LongRunningThreshold is a running duration after which task is counted as long running, disabled if zero
+	LongRunningThreshold time.Duration
+}
+
+// Validate checks configuration
+func (c Config) Validate() error {
+	if c.TopN < 0 {
+		return fmt.Errorf("top N must not be negative, got %d", c.TopN)
+	}
+	if c.LongRunningThreshold < 0 {
+		return fmt.Errorf("long running threshold must not be negative, got %s", c.LongRunningThreshold)
+	}
+	for i := 1; i < len(c.DurationBuckets); i++ {
+		if c.DurationBuckets[i] <= c.DurationBuckets[i-1] {
+			return fmt.Errorf("duration buckets must be in increasing order")
+		}
+	}
+
+	return nil
+}
+
 // Label values extractors for different kind of metrics
 var (
 	labelValuesTasks = func(action, host, cluster string) []string {
@@ -22,82 +64,326 @@ var (
 	}
 )
 
-type taskMetric struct {
+type taskGroupMetric struct {
 	*metrics.Metric
-	Value func(t float64) float64
+	Value func(g *taskGroup) float64
+}
+
+// taskGroup is an aggregate of tasks with the same action running on the same node
+type taskGroup struct {
+	count       float64
+	maxTime     float64
+	sumTime     float64
+	cancellable float64
+	cancelled   float64
+	parents     float64
+	children    float64
+}
+
+type taskGroupKey struct {
+	action string
+	node   string
+}
+
+type runningTask struct {
+	id   string
+	node string
+	task model.Task
 }
 
 // Collector is an tasks metrics collector
 type Collector struct {
-	esClient   elasticsearch.Client
-	taskMetric *taskMetric
-	maxTasks   map[string]max
-}
+	esClient             elasticsearch.Client
+	topN                 int
+	durationBuckets      []float64
+	longRunningThreshold time.Duration
+
+	taskGroupMetrics      []*taskGroupMetric
+	longestTaskMetric     *metrics.Metric
+	durationHistogramDesc *prometheus.Desc
+	longRunningMetric     *metrics.Metric
 
-type max struct {
-	time      float64
-	taskIndex int
+	// mu guards long running tasks state shared between scrapes
+	mu sync.Mutex
+	// longRunning are IDs of running tasks which have already crossed the threshold
+	longRunning map[string]bool
+	// longRunningTotal is a number of tasks crossed the threshold per action
+	longRunningTotal map[string]float64
 }
 
-func newTaskMetric(t prometheus.ValueType, name, help string, valueExtractor func(t float64) float64) *taskMetric {
-	return &taskMetric{
+func newTaskGroupMetric(t prometheus.ValueType, name, help string, valueExtractor func(g *taskGroup) float64) *taskGroupMetric {
+	return &taskGroupMetric{
 		Metric: metrics.New(t, "", name, help, labelsTasks),
 		Value:  valueExtractor,
 	}
 }
 
-// NewCollector returns new nodes metrics collector
-func NewCollector(esClient elasticsearch.Client) *Collector {
+// NewCollector returns new tasks metrics collector
+func NewCollector(esClient elasticsearch.Client, config Config) *Collector {
+	buckets := config.DurationBuckets
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+
 	return &Collector{
-		esClient: esClient,
-		taskMetric: newTaskMetric(
-			prometheus.GaugeValue, "task_group_duration_seconds", "Task group running duration in seconds",
-			func(t float64) float64 { return t },
+		esClient:             esClient,
+		topN:                 config.TopN,
+		durationBuckets:      buckets,
+		longRunningThreshold: config.LongRunningThreshold,
+		longRunning:          make(map[string]bool),
+		longRunningTotal:     make(map[string]float64),
+
+		taskGroupMetrics: []*taskGroupMetric{
+			newTaskGroupMetric(
+				prometheus.GaugeValue, "task_group_duration_seconds", "Task group running duration in seconds",
+				func(g *taskGroup) float64 { return g.maxTime },
+			),
+			newTaskGroupMetric(
+				prometheus.GaugeValue, "task_group_total_duration_seconds", "Sum of running durations of tasks in group in seconds",
+				func(g *taskGroup) float64 { return g.sumTime },
+			),
+			newTaskGroupMetric(
+				prometheus.GaugeValue, "task_group_tasks", "Number of running tasks in group",
+				func(g *taskGroup) float64 { return g.count },
+			),
+			newTaskGroupMetric(
+				prometheus.GaugeValue, "task_group_cancellable_tasks", "Number of cancellable running tasks in group",
+				func(g *taskGroup) float64 { return g.cancellable },
+			),
+			newTaskGroupMetric(
+				prometheus.GaugeValue, "task_group_cancelled_tasks", "Number of cancelled but still running tasks in group",
+				func(g *taskGroup) float64 { return g.cancelled },
+			),
+			newTaskGroupMetric(
+				prometheus.GaugeValue, "task_group_parent_tasks", "Number of running tasks in group having running child tasks",
+				func(g *taskGroup) float64 { return g.parents },
+			),
+			newTaskGroupMetric(
+				prometheus.GaugeValue, "task_group_child_tasks", "Number of running tasks in group spawned by another task",
+				func(g *taskGroup) float64 { return g.children },
+			),
+		},
+		longestTaskMetric: metrics.New(
+			prometheus.GaugeValue, "", "task_longest_duration_seconds",
+			"Running duration of the longest running tasks in seconds",
+			labelsLongestTasks,
+		),
+		durationHistogramDesc: metrics.NewDesc(
+			"", "task_duration_seconds",
+			"Histogram of running durations of currently running tasks in seconds",
+			labelsTaskActions, nil,
+		),
+		longRunningMetric: metrics.New(
+			prometheus.CounterValue, "", "task_long_running_total",
+			"Number of tasks which have been running longer than the threshold",
+			labelsTaskActions,
 		),
-		maxTasks: make(map[string]max),
 	}
 }
 
 // Describe implements prometheus.Collector interface
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- c.taskMetric.Desc()
+	for _, metric := range c.taskGroupMetrics {
+		ch <- metric.Desc()
+	}
+	if c.topN > 0 {
+		ch <- c.longestTaskMetric.Desc()
+	}
+	ch <- c.durationHistogramDesc
+	if c.longRunningThreshold > 0 {
+		ch <- c.longRunningMetric.Desc()
+	}
 }
 
 // Collect writes data to metrics channel
-func (c *Collector) Collect(clusterName string, ch chan<- prometheus.Metric) {
-	tasks, err := c.esClient.Tasks()
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	tasks, err := c.esClient.Tasks(ctx)
+	if errors.Is(err, elasticsearch.ErrNotSupported) {
+		return
+	}
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		log.Println("WARN: skipped fetching tasks: ", err)
+		return
+	}
 	if err != nil {
-		log.Println("ERROR: failed to fetch nodes stats: ", err)
+		log.Println("ERROR: failed to fetch tasks: ", err)
 		return
 	}
 
-	for x := range c.maxTasks {
-		delete(c.maxTasks, x)
+	running := flatten(tasks)
+
+	parents := make(map[string]bool)
+	for _, t := range running {
+		if t.task.ParentTaskID != "" {
+			parents[t.task.ParentTaskID] = true
+		}
 	}
 
-	// Select only max running time for particular task and node
-	for i, task := range *tasks {
-		t, err := time.ParseDuration(strings.Replace(task.RunningTime, "micros", "us", 1))
-		if err != nil {
-			log.Println("ERROR: failed to parse time: ", err)
-			continue
+	groups := make(map[taskGroupKey]*taskGroup)
+	for _, t := range running {
+		key := taskGroupKey{action: t.task.Action, node: t.node}
+		g, ok := groups[key]
+		if !ok {
+			g = &taskGroup{}
+			groups[key] = g
+		}
+
+		runningTime := runningSeconds(t.task)
+		g.count++
+		g.sumTime += runningTime
+		if runningTime > g.maxTime {
+			g.maxTime = runningTime
+		}
+		if t.task.Cancellable {
+			g.cancellable++
 		}
+		if t.task.Cancelled {
+			g.cancelled++
+		}
+		if parents[t.id] {
+			g.parents++
+		}
+		if t.task.ParentTaskID != "" {
+			g.children++
+		}
+	}
 
-		taskTime := float64(t) / float64(time.Second)
-		key := task.Action + "|" + task.Node
-		maxItem, has := c.maxTasks[key]
-		if !has || taskTime > maxItem.time {
-			c.maxTasks[key] = max{time: taskTime, taskIndex: i}
+	for key, g := range groups {
+		for _, metric := range c.taskGroupMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc(),
+				metric.Type(),
+				metric.Value(g),
+				labelValuesTasks(key.action, key.node, clusterName)...,
+			)
 		}
 	}
 
-	for _, max := range c.maxTasks {
-		task := (*tasks)[max.taskIndex]
+	c.collectDurations(clusterName, running, ch)
+
+	if c.longRunningThreshold > 0 {
+		c.collectLongRunning(clusterName, running, ch)
+	}
+
+	if c.topN > 0 {
+		c.collectLongest(clusterName, running, ch)
+	}
+}
+
+// collectDurations exports histogram of running durations per action.
+// It is a snapshot of currently running tasks, so its count and sum may decrease.
+func (c *Collector) collectDurations(clusterName string, running []runningTask, ch chan<- prometheus.Metric) {
+	type histogram struct {
+		count   uint64
+		sum     float64
+		buckets map[float64]uint64
+	}
+
+	histograms := make(map[string]*histogram)
+	for _, t := range running {
+		h, ok := histograms[t.task.Action]
+		if !ok {
+			h = &histogram{buckets: make(map[float64]uint64, len(c.durationBuckets))}
+			for _, bound := range c.durationBuckets {
+				h.buckets[bound] = 0
+			}
+			histograms[t.task.Action] = h
+		}
+
+		runningTime := runningSeconds(t.task)
+		h.count++
+		h.sum += runningTime
+		for _, bound := range c.durationBuckets {
+			if runningTime <= bound {
+				h.buckets[bound]++
+			}
+		}
+	}
+
+	for action, h := range histograms {
+		ch <- prometheus.MustNewConstHistogram(
+			c.durationHistogramDesc,
+			h.count, h.sum, h.buckets,
+			action, clusterName,
+		)
+	}
+}
+
+// collectLongRunning counts tasks crossed the threshold. Tasks are tracked by ID across scrapes,
+// so each task is counted once. IDs of finished tasks are forgotten.
+func (c *Collector) collectLongRunning(clusterName string, running []runningTask, ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	threshold := c.longRunningThreshold.Nanoseconds()
+	seen := make(map[string]bool, len(c.longRunning))
+	for _, t := range running {
+		if t.task.RunningTimeInNanos < threshold {
+			continue
+		}
+		if !c.longRunning[t.id] {
+			c.longRunningTotal[t.task.Action]++
+		}
+		seen[t.id] = true
+	}
+	c.longRunning = seen
+
+	for action, total := range c.longRunningTotal {
+		ch <- prometheus.MustNewConstMetric(
+			c.longRunningMetric.Desc(),
+			c.longRunningMetric.Type(),
+			total,
+			action, clusterName,
+		)
+	}
+}
+
+func (c *Collector) collectLongest(clusterName string, running []runningTask, ch chan<- prometheus.Metric) {
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].task.RunningTimeInNanos > running[j].task.RunningTimeInNanos
+	})
+	if len(running) > c.topN {
+		running = running[:c.topN]
+	}
+
+	for _, t := range running {
 		ch <- prometheus.MustNewConstMetric(
-			c.taskMetric.Desc(),
-			c.taskMetric.Type(),
-			c.taskMetric.Value(max.time),
-			labelValuesTasks(task.Action, task.Node, clusterName)...,
+			c.longestTaskMetric.Desc(),
+			c.longestTaskMetric.Type(),
+			runningSeconds(t.task),
+			t.task.Action, t.node, clusterName, t.id, truncate(t.task.Description, maxDescriptionLength),
 		)
 	}
 }
+
+// flatten returns tasks of all nodes, node is identified by its name
+func flatten(tasks *model.Tasks) []runningTask {
+	var result []runningTask
+	for nodeID, node := range tasks.Nodes {
+		name := node.Name
+		if name == "" {
+			name = nodeID
+		}
+		for id, task := range node.Tasks {
+			if id == "" {
+				id = task.Node + ":" + strconv.FormatInt(task.ID, 10)
+			}
+			result = append(result, runningTask{id: id, node: name, task: task})
+		}
+	}
+
+	return result
+}
+
+func runningSeconds(task model.Task) float64 {
+	return float64(task.RunningTimeInNanos) / float64(time.Second)
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+
+	return fmt.Sprintf("%s...", string(runes[:length]))
+}
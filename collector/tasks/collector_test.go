@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/testdata"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func collect(c *Collector) map[string]*dto.Metric {
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(context.Background(), "test-cluster", ch)
+	close(ch)
+
+	result := make(map[string]*dto.Metric)
+	for m := range ch {
+		var metric dto.Metric
+		m.Write(&metric)
+
+		key := m.Desc().String()
+		for _, label := range metric.Label {
+			if label.GetName() == "action" {
+				key = label.GetValue() + " " + key
+			}
+		}
+		result[key] = &metric
+	}
+
+	return result
+}
+
+func newESClient() elasticsearch.Client {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/_tasks?detailed=true").WillReturn(200, testdata.TasksBody)
+
+	return elasticsearch.NewClient(mockHTTPClient)
+}
+
+func TestCollector_DurationHistogram(t *testing.T) {
+	c := NewCollector(newESClient(), Config{DurationBuckets: []float64{1, 1.5, 2}})
+
+	var histogram *dto.Histogram
+	for key, m := range collect(c) {
+		if m.Histogram != nil && strings.HasPrefix(key, "indices:data/read/search ") {
+			histogram = m.Histogram
+		}
+	}
+	if histogram == nil {
+		t.Fatalf("Histogram of search tasks not found")
+	}
+
+	want := []uint64{0, 0, 1}
+	for i, bucket := range histogram.Bucket {
+		if bucket.GetCumulativeCount() != want[i] {
+			t.Fatalf("Unexpected count of bucket %v: want %d, got %d", bucket.GetUpperBound(), want[i], bucket.GetCumulativeCount())
+		}
+	}
+	if histogram.GetSampleCount() != 1 || histogram.GetSampleSum() != 1.534222 {
+		t.Fatalf("Unexpected histogram count and sum: %d, %v", histogram.GetSampleCount(), histogram.GetSampleSum())
+	}
+}
+
+func TestCollector_LongRunning(t *testing.T) {
+	c := NewCollector(newESClient(), Config{LongRunningThreshold: 1500 * time.Millisecond})
+
+	// the same task is running during both scrapes, so it is counted once
+	for i := 0; i < 2; i++ {
+		// mocked response body can be read only once
+		c.esClient = newESClient()
+
+		var total float64
+		found := false
+		for _, m := range collect(c) {
+			if m.Counter != nil {
+				total += m.Counter.GetValue()
+				found = true
+			}
+		}
+		if !found || total != 1 {
+			t.Fatalf("Unexpected long running tasks total on scrape %d: %v", i, total)
+		}
+	}
+}
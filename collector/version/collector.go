@@ -0,0 +1,50 @@
+package version
+
+import (
+	"context"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a metrics collector for detected cluster distribution and version
+type Collector struct {
+	esClient elasticsearch.Client
+
+	versionInfoMetric *metrics.Metric
+}
+
+// NewCollector returns new metrics collector for cluster version
+func NewCollector(esClient elasticsearch.Client) *Collector {
+	return &Collector{
+		esClient: esClient,
+		versionInfoMetric: metrics.New(
+			prometheus.GaugeValue,
+			"version",
+			"info",
+			"Detected cluster distribution and version with const value = 1",
+			[]string{"cluster", "distribution", "version", "lucene_version"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.versionInfoMetric.Desc()
+}
+
+// Collect writes data to metrics channel
+func (c *Collector) Collect(ctx context.Context, clusterName string, ch chan<- prometheus.Metric) {
+	version := c.esClient.Version()
+	if !version.Known() {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.versionInfoMetric.Desc(),
+		c.versionInfoMetric.Type(),
+		1,
+		clusterName, version.Distribution, version.Number, version.Lucene,
+	)
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/canary"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/custom"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/freshness"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indexgroup"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/query"
+)
+
+// Config is an exporter configuration file representation.
+// JSON is used so that ES query DSL can be embedded as is.
+type Config struct {
+	IndexGroups   []indexgroup.Rule     `json:"index_groups"`
+	CustomMetrics []custom.MetricConfig `json:"custom_metrics"`
+	Queries       []query.Config        `json:"queries"`
+	Freshness     []freshness.Config    `json:"freshness"`
+	// Canary enables synthetic probes if set
+	Canary *canary.Config `json:"canary"`
+}
+
+// Load reads configuration from JSON file
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var c Config
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
@@ -1,134 +1,401 @@
 package elasticsearch
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient/decorator"
 )
 
-type clusterHealthLevel string
+// ClusterHealthLevel is a level of details of cluster health response
+type ClusterHealthLevel string
 
 // Cluster health levels
 const (
-	LevelCluster clusterHealthLevel = "cluster"
-	LevelIndices clusterHealthLevel = "indices"
-	LevelShards  clusterHealthLevel = "shards"
+	LevelCluster ClusterHealthLevel = "cluster"
+	LevelIndices ClusterHealthLevel = "indices"
+	LevelShards  ClusterHealthLevel = "shards"
 )
 
+// DefaultVersionRefreshInterval is an interval of cluster version re-detection
+const DefaultVersionRefreshInterval = 5 * time.Minute
+
+// ErrNotSupported is returned for APIs which don't exist on the detected cluster version
+var ErrNotSupported = errors.New("not supported by cluster version")
+
 // Client is an ElasticSearch client interface
 type Client interface {
-	ClusterHealth(level clusterHealthLevel) (*model.ClusterHealth, error)
-	Aliases() (model.Aliases, error)
-	Indices() (*model.Indices, error)
-	Nodes(fetchAllNodesInfo bool) (*model.Nodes, error)
-	Recovery() (model.Recovery, error)
-	Tasks() (*model.Tasks, error)
+	Info(ctx context.Context) (*model.Info, error)
+	Version() Version
+	ClusterHealth(ctx context.Context, level ClusterHealthLevel) (*model.ClusterHealth, error)
+	Aliases(ctx context.Context) (model.Aliases, error)
+	Indices(ctx context.Context) (*model.Indices, error)
+	IndicesStream(ctx context.Context, fields StatsFields, fn func(name string, index model.Index)) error
+	Nodes(ctx context.Context, fetchAllNodesInfo bool) (*model.Nodes, error)
+	NodesStream(ctx context.Context, fetchAllNodesInfo bool, fields StatsFields, fn func(node model.Node)) error
+	Recovery(ctx context.Context, activeOnly bool) (model.Recovery, error)
+	Tasks(ctx context.Context) (*model.Tasks, error)
+	Lifecycle(ctx context.Context) (model.Lifecycle, error)
+	SegmentReplication(ctx context.Context) (model.SegmentReplication, error)
+	Search(ctx context.Context, index string, body io.Reader) (*model.SearchResponse, error)
+	Count(ctx context.Context, index string, body io.Reader) (*model.CountResponse, error)
+	Request(ctx context.Context, method, path string, body io.Reader, v interface{}) error
 }
 
 var (
 	_ Client = &ESClient{}
 )
 
+// Option is an ESClient configuration option
+type Option func(*ESClient)
+
+// WithIndexFilter returns an Option that limits per-index responses to indices matching given filter
+func WithIndexFilter(filter *IndexFilter) Option {
+	return func(c *ESClient) {
+		c.indexFilter = filter
+	}
+}
+
+// WithVersionRefreshInterval returns an Option that sets interval of cluster version re-detection
+func WithVersionRefreshInterval(interval time.Duration) Option {
+	return func(c *ESClient) {
+		c.versionRefreshInterval = interval
+	}
+}
+
+// WithCompatibility returns an Option that makes requests to Elasticsearch of newer major version than given one
+// with REST API compatibility headers, so responses are in format of given version.
+// Headers aren't sent until the version is detected, and they are never sent to OpenSearch
+func WithCompatibility(compatibleWith int) Option {
+	return func(c *ESClient) {
+		c.compatibleWith = compatibleWith
+		c.compatibleClient = decorator.CompatibilityDecorator(compatibleWith)(c.httpClient)
+	}
+}
+
 // NewClient returns new client
-func NewClient(httpClient httpclient.Client) *ESClient {
-	return &ESClient{
-		httpClient: httpClient,
+func NewClient(httpClient httpclient.Client, options ...Option) *ESClient {
+	c := &ESClient{
+		httpClient:             httpClient,
+		versionRefreshInterval: DefaultVersionRefreshInterval,
+	}
+
+	for _, option := range options {
+		option(c)
 	}
+
+	return c
 }
 
 // Client is an ElasticSearch client implementation
 type ESClient struct {
-	httpClient httpclient.Client
+	httpClient  httpclient.Client
+	indexFilter *IndexFilter
+
+	compatibleWith   int
+	compatibleClient httpclient.Client
+
+	versionRefreshInterval time.Duration
+	versionMu              sync.Mutex
+	version                atomic.Value
+	versionCheckedAt       time.Time
+}
+
+// Info returns ES root endpoint info with cluster name and version
+func (c *ESClient) Info(ctx context.Context) (*model.Info, error) {
+	var v model.Info
+	if err := c.makeRequest(ctx, "/", &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// Version returns detected distribution and version of the cluster.
+// Version is detected on the first call and re-detected once per refresh interval,
+// the last detected version is kept if detection fails. Detection isn't a part of any request context,
+// because the version is shared by all scrapes
+func (c *ESClient) Version() Version {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+
+	last := c.detectedVersion()
+	if !c.versionCheckedAt.IsZero() && time.Since(c.versionCheckedAt) < c.versionRefreshInterval {
+		return last
+	}
+	c.versionCheckedAt = time.Now()
+
+	info, err := c.Info(context.Background())
+	if err != nil {
+		log.Println("ERROR: failed to detect cluster version: ", err)
+		return last
+	}
+	version, err := ParseVersion(info)
+	if err != nil {
+		log.Println("ERROR: failed to detect cluster version: ", err)
+		return last
+	}
+
+	if version != last {
+		log.Println("Detected cluster version:", version)
+	}
+	c.version.Store(version)
+
+	return version
+}
+
+// detectedVersion returns the last detected version without re-detection
+func (c *ESClient) detectedVersion() Version {
+	version, _ := c.version.Load().(Version)
+	return version
 }
 
 // ClusterHealth returns ES cluster health info
-func (c *ESClient) ClusterHealth(level clusterHealthLevel) (*model.ClusterHealth, error) {
+func (c *ESClient) ClusterHealth(ctx context.Context, level ClusterHealthLevel) (*model.ClusterHealth, error) {
 	var v model.ClusterHealth
-	if err := c.makeRequest("/_cluster/health?level="+string(level), &v); err != nil {
+	if err := c.makeRequest(ctx, "/_cluster/health?level="+string(level), &v); err != nil {
 		return nil, err
 	}
 
+	// cluster-wide values are kept as is, only index level is filtered
+	for name := range v.Indices {
+		if !c.indexFilter.Match(name) {
+			delete(v.Indices, name)
+		}
+	}
+
 	return &v, nil
 }
 
 // Aliases returns ES index aliases info
-func (c *ESClient) Aliases() (model.Aliases, error) {
+func (c *ESClient) Aliases(ctx context.Context) (model.Aliases, error) {
 	var v model.Aliases
-	if err := c.makeRequest("/_aliases", &v); err != nil {
+	if err := c.makeRequest(ctx, "/_aliases", &v); err != nil {
 		return nil, err
 	}
 
+	for name := range v {
+		if !c.indexFilter.Match(name) {
+			delete(v, name)
+		}
+	}
+
 	return v, nil
 }
 
 // Indices returns ES indices info
-func (c *ESClient) Indices() (*model.Indices, error) {
+func (c *ESClient) Indices(ctx context.Context) (*model.Indices, error) {
+	version := c.Version()
+
 	var v model.Indices
-	if err := c.makeRequest("/_stats", &v); err != nil {
+	if err := c.makeRequest(ctx, c.indexFilter.indicesPath(version, "_stats", nil), &v); err != nil {
 		return nil, err
 	}
+	if !version.atLeast(2, 0) {
+		upgradeIndices1x(&v)
+	}
+
+	for name := range v.Indices {
+		if !c.indexFilter.Match(name) {
+			delete(v.Indices, name)
+		}
+	}
 
 	return &v, nil
 }
 
 // Nodes returns ES nodes info
-func (c *ESClient) Nodes(fetchAllNodesInfo bool) (*model.Nodes, error) {
-	path := "/_nodes/_local/stats"
-	if fetchAllNodesInfo {
-		path = "/_nodes/stats"
-	}
+func (c *ESClient) Nodes(ctx context.Context, fetchAllNodesInfo bool) (*model.Nodes, error) {
+	path := nodesPath(fetchAllNodesInfo)
+	version := c.Version()
 
 	var v model.Nodes
-	if err := c.makeRequest(path, &v); err != nil {
+	if err := c.makeRequest(ctx, path, &v); err != nil {
 		return nil, err
 	}
+	if !version.atLeast(2, 0) {
+		upgradeNodes1x(&v)
+	}
 
 	return &v, nil
 }
 
-// Tasks returns ES tasks info
-func (c *ESClient) Tasks() (*model.Tasks, error) {
-	path := "/_cat/tasks?format=json"
+func nodesPath(fetchAllNodesInfo bool) string {
+	if fetchAllNodesInfo {
+		return "/_nodes/stats"
+	}
+	return "/_nodes/_local/stats"
+}
+
+// Tasks returns currently running ES tasks with descriptions
+func (c *ESClient) Tasks(ctx context.Context) (*model.Tasks, error) {
+	if !c.Version().atLeast(5, 0) {
+		return nil, fmt.Errorf("tasks API: %w", ErrNotSupported)
+	}
+
+	path := "/_tasks?detailed=true"
 
 	var v model.Tasks
-	if err := c.makeRequest(path, &v); err != nil {
+	if err := c.makeRequest(ctx, path, &v); err != nil {
 		return nil, err
 	}
 
 	return &v, nil
 }
 
-// Recovery returns ES state with currently active recovery operations
-func (c *ESClient) Recovery() (model.Recovery, error) {
+// Recovery returns ES shards recovery state. If activeOnly is false, completed recoveries are returned too
+func (c *ESClient) Recovery(ctx context.Context, activeOnly bool) (model.Recovery, error) {
 	var v model.Recovery
-	path := "/_recovery?active_only=true"
+	var params url.Values
+	if activeOnly {
+		params = url.Values{"active_only": {"true"}}
+	}
+	path := c.indexFilter.indicesPath(c.Version(), "_recovery", params)
 
-	if err := c.makeRequest(path, &v); err != nil {
+	if err := c.makeRequest(ctx, path, &v); err != nil {
+		return nil, err
+	}
+
+	for name := range v {
+		if !c.indexFilter.Match(name) {
+			delete(v, name)
+		}
+	}
+
+	return v, nil
+}
+
+// Lifecycle returns lifecycle state of managed indices from ILM on Elasticsearch 6.6+ or ISM on OpenSearch
+func (c *ESClient) Lifecycle(ctx context.Context) (model.Lifecycle, error) {
+	version := c.Version()
+
+	var (
+		v   model.Lifecycle
+		err error
+	)
+	switch {
+	case version.IsOpenSearch():
+		v, err = c.ismExplain(ctx)
+	case version.atLeast(6, 6):
+		v, err = c.ilmExplain(ctx)
+	default:
+		return nil, fmt.Errorf("index lifecycle management: %w", ErrNotSupported)
+	}
+	if err != nil {
 		return nil, err
 	}
 
+	for name := range v {
+		if !c.indexFilter.Match(name) {
+			delete(v, name)
+		}
+	}
+
 	return v, nil
 }
 
+// SegmentReplication returns replication state of replica shards of indices with segment replication.
+// Segment replication is available on OpenSearch 2.7+ only
+func (c *ESClient) SegmentReplication(ctx context.Context) (model.SegmentReplication, error) {
+	if !c.Version().openSearchAtLeast(2, 7) {
+		return nil, fmt.Errorf("segment replication: %w", ErrNotSupported)
+	}
+
+	var v model.SegmentReplication
+	if err := c.makeRequest(ctx, "/_cat/segment_replication?format=json&bytes=b&time=ms", &v); err != nil {
+		return nil, err
+	}
+
+	filtered := v[:0]
+	for _, shard := range v {
+		if index, _ := shard.Shard(); c.indexFilter.Match(index) {
+			filtered = append(filtered, shard)
+		}
+	}
+
+	return filtered, nil
+}
+
+// Search runs search request against given index pattern
+func (c *ESClient) Search(ctx context.Context, index string, body io.Reader) (*model.SearchResponse, error) {
+	var v model.SearchResponse
+	if err := c.Request(ctx, "POST", "/"+index+"/_search", body, &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// Count runs count request against given index pattern
+func (c *ESClient) Count(ctx context.Context, index string, body io.Reader) (*model.CountResponse, error) {
+	var v model.CountResponse
+	if err := c.Request(ctx, "POST", "/"+index+"/_count", body, &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// Request sends arbitrary request to ES and decodes JSON response to given value
+func (c *ESClient) Request(ctx context.Context, method, path string, body io.Reader, v interface{}) error {
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.do(req.WithContext(ctx), v)
+}
+
 // makeRequest sends request and encodes it to given struct
-func (c *ESClient) makeRequest(path string, v interface{}) error {
+func (c *ESClient) makeRequest(ctx context.Context, path string, v interface{}) error {
 	req, err := http.NewRequest("GET", path, nil)
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	return c.do(req.WithContext(ctx), v)
+}
+
+// do sends request and decodes JSON response to given value
+func (c *ESClient) do(req *http.Request, v interface{}) error {
+	return c.send(req, func(dec *json.Decoder) error {
+		return dec.Decode(v)
+	})
+}
+
+// send sends request and passes JSON decoder of successful response body to decode
+func (c *ESClient) send(req *http.Request, decode func(*json.Decoder) error) error {
+	client := c.httpClient
+	if c.compatibleClient != nil {
+		if version := c.detectedVersion(); version.Known() && !version.IsOpenSearch() && version.Major > c.compatibleWith {
+			client = c.compatibleClient
+		}
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 
 	defer resp.Body.Close()
 
-	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
-		return err
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status code %d for %s %s", resp.StatusCode, req.Method, req.URL.Path)
 	}
 
-	return nil
+	return decode(json.NewDecoder(resp.Body))
 }
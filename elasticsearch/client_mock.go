@@ -6,7 +6,7 @@ import (
 
 // ClientMock is a client mock implementation
 type ClientMock struct {
-	ClusterHealthCallback func(level clusterHealthLevel) (*model.ClusterHealth, error)
+	ClusterHealthCallback func(level ClusterHealthLevel) (*model.ClusterHealth, error)
 	AliasesCallback       func() (model.Aliases, error)
 	IndicesCallback       func() (*model.Indices, error)
 	NodesCallback         func(fetchAllNodesInfo bool) (*model.Nodes, error)
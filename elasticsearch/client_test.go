@@ -1,9 +1,16 @@
 package elasticsearch
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/testdata"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
 )
@@ -13,7 +20,7 @@ func TestClient_Aliases_Ok(t *testing.T) {
 	mockHTTPClient.Get("/_aliases").WillReturn(200, testdata.AliasesBody)
 
 	esClient := NewClient(mockHTTPClient)
-	got, err := esClient.Aliases()
+	got, err := esClient.Aliases(context.Background())
 
 	if err != nil {
 		t.Fatalf("Error on getting ES aliases: %s", err)
@@ -23,12 +30,18 @@ func TestClient_Aliases_Ok(t *testing.T) {
 	}
 }
 
+func TestAliases_WriteIndices(t *testing.T) {
+	if got := testdata.Aliases.WriteIndices(); !reflect.DeepEqual(testdata.AliasesWriteIndices, got) {
+		t.Fatalf("Write indices are not equal: want %v, got %v", testdata.AliasesWriteIndices, got)
+	}
+}
+
 func TestClient_Aliases_Error(t *testing.T) {
 	mockHTTPClient := httpclient.NewClientMock()
 	mockHTTPClient.Get("/_aliases").WillReturn(500, ``)
 
 	esClient := NewClient(mockHTTPClient)
-	_, err := esClient.Aliases()
+	_, err := esClient.Aliases(context.Background())
 
 	if err == nil {
 		t.Fatalf("Error expected, got nil")
@@ -40,7 +53,7 @@ func TestClient_ClusterHealth_Ok(t *testing.T) {
 	mockHTTPClient.Get("/_cluster/health?level=indices").WillReturn(200, testdata.ClusterHealthIndicesBody)
 
 	esClient := NewClient(mockHTTPClient)
-	got, err := esClient.ClusterHealth(LevelIndices)
+	got, err := esClient.ClusterHealth(context.Background(), LevelIndices)
 
 	if err != nil {
 		t.Fatalf("Error on getting ES cluster health: %s", err)
@@ -51,12 +64,27 @@ func TestClient_ClusterHealth_Ok(t *testing.T) {
 	}
 }
 
+func TestClient_ClusterHealth_Shards(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/_cluster/health?level=shards").WillReturn(200, testdata.ClusterHealthShardsBody)
+
+	esClient := NewClient(mockHTTPClient)
+	got, err := esClient.ClusterHealth(context.Background(), LevelShards)
+
+	if err != nil {
+		t.Fatalf("Error on getting ES cluster health: %s", err)
+	}
+	if !reflect.DeepEqual(testdata.ClusterHealthShards, got) {
+		t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.ClusterHealthShards, got)
+	}
+}
+
 func TestClient_ClusterHealth_Error(t *testing.T) {
 	mockHTTPClient := httpclient.NewClientMock()
 	mockHTTPClient.Get("/_cluster/health?level=cluster").WillReturn(500, ``)
 
 	esClient := NewClient(mockHTTPClient)
-	_, err := esClient.ClusterHealth(LevelCluster)
+	_, err := esClient.ClusterHealth(context.Background(), LevelCluster)
 
 	if err == nil {
 		t.Fatalf("Error expected, got nil")
@@ -68,7 +96,7 @@ func TestClient_NodesAll_Ok(t *testing.T) {
 	mockHTTPClient.Get("/_nodes/stats").WillReturn(200, testdata.NodesBody)
 
 	esClient := NewClient(mockHTTPClient)
-	nodes, err := esClient.Nodes(true)
+	nodes, err := esClient.Nodes(context.Background(), true)
 
 	if err != nil {
 		t.Fatalf("Error on getting ES nodes stats: %s", err)
@@ -79,12 +107,29 @@ func TestClient_NodesAll_Ok(t *testing.T) {
 	}
 }
 
+func TestClient_Nodes_IOStats(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/_nodes/stats").WillReturn(200, testdata.NodesBody)
+
+	esClient := NewClient(mockHTTPClient)
+	nodes, err := esClient.Nodes(context.Background(), true)
+
+	if err != nil {
+		t.Fatalf("Error on getting ES nodes stats: %s", err)
+	}
+
+	got := nodes.Nodes["3a6VFkY8SLOI4J6ljALdhQ"].FS.IOStats
+	if !reflect.DeepEqual(testdata.NodesIOStats, got) {
+		t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.NodesIOStats, got)
+	}
+}
+
 func TestClient_NodesAll_Error(t *testing.T) {
 	mockHTTPClient := httpclient.NewClientMock()
 	mockHTTPClient.Get("/_nodes/stats").WillReturn(500, ``)
 
 	esClient := NewClient(mockHTTPClient)
-	_, err := esClient.Nodes(true)
+	_, err := esClient.Nodes(context.Background(), true)
 
 	if err == nil {
 		t.Fatalf("Error expected, got nil")
@@ -96,7 +141,7 @@ func TestClient_NodesSelf_Ok(t *testing.T) {
 	mockHTTPClient.Get("/_nodes/_local/stats").WillReturn(200, testdata.NodesBody)
 
 	esClient := NewClient(mockHTTPClient)
-	_, err := esClient.Nodes(false)
+	_, err := esClient.Nodes(context.Background(), false)
 
 	if err != nil {
 		t.Fatalf("Error on getting ES nodes stats: %s", err)
@@ -108,7 +153,7 @@ func TestClient_Recovery_Ok(t *testing.T) {
 	mockHTTPClient.Get("/_recovery?active_only=true").WillReturn(200, testdata.RecoveryBody)
 
 	esClient := NewClient(mockHTTPClient)
-	got, err := esClient.Recovery()
+	got, err := esClient.Recovery(context.Background(), true)
 
 	if err != nil {
 		t.Fatalf("Error on getting ES recovery state: %s", err)
@@ -118,3 +163,458 @@ func TestClient_Recovery_Ok(t *testing.T) {
 		t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.Recovery, got)
 	}
 }
+
+func TestClient_Tasks_Ok(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/_tasks?detailed=true").WillReturn(200, testdata.TasksBody)
+
+	esClient := NewClient(mockHTTPClient)
+	got, err := esClient.Tasks(context.Background())
+
+	if err != nil {
+		t.Fatalf("Error on getting ES tasks: %s", err)
+	}
+
+	if !reflect.DeepEqual(&testdata.Tasks, got) {
+		t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.Tasks, got)
+	}
+}
+
+func TestClient_Recovery_All(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/_recovery").WillReturn(200, testdata.RecoveryBody)
+
+	esClient := NewClient(mockHTTPClient)
+	got, err := esClient.Recovery(context.Background(), false)
+
+	if err != nil {
+		t.Fatalf("Error on getting ES recovery state: %s", err)
+	}
+
+	if !reflect.DeepEqual(testdata.Recovery, got) {
+		t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.Recovery, got)
+	}
+}
+
+func TestClient_Info_Ok(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.InfoBody)
+
+	esClient := NewClient(mockHTTPClient)
+	got, err := esClient.Info(context.Background())
+
+	if err != nil {
+		t.Fatalf("Error on getting ES info: %s", err)
+	}
+	if !reflect.DeepEqual(testdata.Info, got) {
+		t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.Info, got)
+	}
+}
+
+func TestClient_Version(t *testing.T) {
+	tests := map[string]struct {
+		body string
+		want Version
+	}{
+		"elasticsearch": {
+			body: testdata.InfoBody,
+			want: Version{Distribution: DistributionElasticsearch, Number: "7.10.2", Major: 7, Minor: 10, Lucene: "8.7.0"},
+		},
+		"opensearch": {
+			body: testdata.InfoOpenSearchBody,
+			want: Version{Distribution: DistributionOpenSearch, Number: "2.11.0", Major: 2, Minor: 11, Lucene: "9.7.0"},
+		},
+		"1.x": {
+			body: testdata.Info1xBody,
+			want: Version{Distribution: DistributionElasticsearch, Number: "1.7.5", Major: 1, Minor: 7, Lucene: "4.10.4"},
+		},
+		"failed": {
+			body: `{}`,
+			want: Version{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockHTTPClient := httpclient.NewClientMock()
+			mockHTTPClient.Get("/").WillReturn(200, tt.body)
+
+			if got := NewClient(mockHTTPClient).Version(); got != tt.want {
+				t.Fatalf("Versions are not equal: want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClient_Version_Cached(t *testing.T) {
+	requests := 0
+	esClient := NewClient(httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(testdata.InfoBody))}, nil
+	}))
+
+	esClient.Version()
+	esClient.Version()
+	if requests != 1 {
+		t.Fatalf("Version must be requested once per refresh interval, got %d requests", requests)
+	}
+
+	esClient.versionCheckedAt = time.Now().Add(-DefaultVersionRefreshInterval)
+	esClient.Version()
+	if requests != 2 {
+		t.Fatalf("Version must be requested again after refresh interval, got %d requests", requests)
+	}
+}
+
+func TestClient_Nodes_1x(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.Info1xBody)
+	mockHTTPClient.Get("/_nodes/stats").WillReturn(200, testdata.Nodes1xBody)
+
+	esClient := NewClient(mockHTTPClient)
+	got, err := esClient.Nodes(context.Background(), true)
+
+	if err != nil {
+		t.Fatalf("Error on getting ES nodes stats: %s", err)
+	}
+	indices := got.Nodes["3a6VFkY8SLOI4J6ljALdhQ"].Indices
+	if indices.RequestCache.MemorySize != 2048 || indices.RequestCache.HitCount != 3 {
+		t.Fatalf("1.x query cache must be decoded as request cache, got %+v", indices.RequestCache)
+	}
+	if indices.QueryCache.MemorySize != 0 || indices.FilterCache.MemorySize != 1024 {
+		t.Fatalf("Unexpected 1.x caches: query cache %+v, filter cache %+v", indices.QueryCache, indices.FilterCache)
+	}
+}
+
+func TestClient_Tasks_NotSupported(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.Info1xBody)
+
+	esClient := NewClient(mockHTTPClient)
+	_, err := esClient.Tasks(context.Background())
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("ErrNotSupported expected, got %v", err)
+	}
+}
+
+func TestClient_Indices_Hidden(t *testing.T) {
+	filter, _ := NewIndexFilter([]string{"twitter*"}, nil, true)
+
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, strings.Replace(testdata.InfoBody, "7.10.2", "7.6.2", 1))
+	mockHTTPClient.Get("/twitter*/_stats?allow_no_indices=true&expand_wildcards=open&ignore_unavailable=true").
+		WillReturn(200, `{"indices": {"twitter": {}}}`)
+
+	esClient := NewClient(mockHTTPClient, WithIndexFilter(filter))
+	if _, err := esClient.Indices(context.Background()); err != nil {
+		t.Fatalf("Hidden indices must not be requested before 7.7: %s", err)
+	}
+}
+
+func TestClient_Lifecycle(t *testing.T) {
+	tests := map[string]struct {
+		info string
+		path string
+		body string
+		want model.Lifecycle
+	}{
+		"ilm": {
+			info: testdata.InfoBody,
+			path: "/*/_ilm/explain",
+			body: testdata.ILMExplainBody,
+			want: testdata.ILMLifecycle,
+		},
+		"ism": {
+			info: testdata.InfoOpenSearchBody,
+			path: "/_plugins/_ism/explain/*",
+			body: testdata.ISMExplainBody,
+			want: testdata.ISMLifecycle,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockHTTPClient := httpclient.NewClientMock()
+			mockHTTPClient.Get("/").WillReturn(200, tt.info)
+			mockHTTPClient.Get(tt.path).WillReturn(200, tt.body)
+
+			got, err := NewClient(mockHTTPClient).Lifecycle(context.Background())
+
+			if err != nil {
+				t.Fatalf("Error on getting index lifecycle state: %s", err)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Fatalf("Structs are not equal: want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClient_Lifecycle_NotSupported(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, strings.Replace(testdata.InfoBody, "7.10.2", "6.5.4", 1))
+
+	_, err := NewClient(mockHTTPClient).Lifecycle(context.Background())
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("ErrNotSupported expected, got %v", err)
+	}
+}
+
+func TestClient_SegmentReplication(t *testing.T) {
+	filter, _ := NewIndexFilter([]string{"logs-*"}, nil, true)
+
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.InfoOpenSearchBody)
+	mockHTTPClient.Get("/_cat/segment_replication?format=json&bytes=b&time=ms").WillReturn(200, testdata.SegmentReplicationBody)
+
+	got, err := NewClient(mockHTTPClient, WithIndexFilter(filter)).SegmentReplication(context.Background())
+
+	if err != nil {
+		t.Fatalf("Error on getting segment replication state: %s", err)
+	}
+	if !reflect.DeepEqual(testdata.SegmentReplication, got) {
+		t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.SegmentReplication, got)
+	}
+	if index, shard := got[0].Shard(); index != "logs-000001" || shard != "0" {
+		t.Fatalf("Unexpected shard %q of index %q", shard, index)
+	}
+}
+
+func TestClient_SegmentReplication_NotSupported(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.InfoBody)
+
+	_, err := NewClient(mockHTTPClient).SegmentReplication(context.Background())
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("ErrNotSupported expected, got %v", err)
+	}
+}
+
+func TestClient_Compatibility(t *testing.T) {
+	const mediaType = "application/vnd.elasticsearch+json; compatible-with=7"
+
+	tests := map[string]struct {
+		info   string
+		accept string
+	}{
+		"8.x":        {info: testdata.Info8Body, accept: mediaType},
+		"7.x":        {info: testdata.InfoBody, accept: ""},
+		"opensearch": {info: testdata.InfoOpenSearchBody, accept: ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockHTTPClient := httpclient.NewClientMock()
+			mockHTTPClient.Get("/").WillReturn(200, tt.info)
+			mockHTTPClient.Get("/_cluster/health?level=cluster").
+				WithHeader("Accept", tt.accept).
+				WillReturn(200, testdata.ClusterHealth8Body)
+
+			esClient := NewClient(mockHTTPClient, WithCompatibility(7))
+			esClient.Version()
+			got, err := esClient.ClusterHealth(context.Background(), LevelCluster)
+
+			if err != nil {
+				t.Fatalf("Error on getting ES cluster health: %s", err)
+			}
+			if got.ActiveShards != 3 || got.ActiveShardsPercentAsNumber != 75 {
+				t.Fatalf("Unexpected cluster health: %+v", got)
+			}
+		})
+	}
+}
+
+func TestClient_Search_8x(t *testing.T) {
+	for name, body := range map[string]string{"native": testdata.Search8Body, "compat": testdata.Search8CompatBody} {
+		t.Run(name, func(t *testing.T) {
+			mockHTTPClient := httpclient.NewClientMock()
+			mockHTTPClient.Post("/logs-*/_search").WillReturn(200, body)
+
+			got, err := NewClient(mockHTTPClient).Search(context.Background(), "logs-*", strings.NewReader(`{}`))
+
+			if err != nil {
+				t.Fatalf("Error on search: %s", err)
+			}
+			if got.Hits.Total.Value != 1 || !reflect.DeepEqual(testdata.Search8Hits, got.Hits.Hits) {
+				t.Fatalf("Unexpected hits: %+v", got.Hits)
+			}
+		})
+	}
+}
+
+func TestClient_Nodes_8x(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.Info8Body)
+	mockHTTPClient.Get("/_nodes/_local/stats").WillReturn(200, testdata.Nodes8Body)
+
+	got, err := NewClient(mockHTTPClient).Nodes(context.Background(), false)
+
+	if err != nil {
+		t.Fatalf("Error on getting ES nodes stats: %s", err)
+	}
+	node := got.Nodes["H8rS3c1fQ6mGQxS2hXvq6A"]
+	if node.Name != "es8-node-1" || node.Indices.Docs.Count != 1500 || node.Indices.Store.Size != 1048576 {
+		t.Fatalf("Unexpected node stats: %+v", node)
+	}
+	if node.Indices.QueryCache.MemorySize != 2048 || node.Indices.RequestCache.HitCount != 7 {
+		t.Fatalf("Unexpected caches: query cache %+v, request cache %+v", node.Indices.QueryCache, node.Indices.RequestCache)
+	}
+}
+
+func TestClient_Indices_8x(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/").WillReturn(200, testdata.Info8Body)
+	mockHTTPClient.Get("/_stats").WillReturn(200, testdata.Indices8Body)
+
+	got, err := NewClient(mockHTTPClient).Indices(context.Background())
+
+	if err != nil {
+		t.Fatalf("Error on getting ES indices stats: %s", err)
+	}
+	index := got.Indices["logs-000001"]
+	if index.Primaries.Docs.Count != 1500 || index.Primaries.Indexing.IndexTotal != 1500 || index.Primaries.RequestCache.HitCount != 7 {
+		t.Fatalf("Unexpected index stats: %+v", index.Primaries)
+	}
+}
+
+func TestClient_Indices_Filtered(t *testing.T) {
+	filter, _ := NewIndexFilter([]string{"twitter*"}, []string{`/-tmp$/`}, false)
+
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/twitter*/_stats?allow_no_indices=true&expand_wildcards=open&ignore_unavailable=true").
+		WillReturn(200, `{"indices": {"twitter": {}, "twitter-tmp": {}}}`)
+
+	esClient := NewClient(mockHTTPClient, WithIndexFilter(filter))
+	got, err := esClient.Indices(context.Background())
+
+	if err != nil {
+		t.Fatalf("Error on getting ES indices stats: %s", err)
+	}
+	if _, ok := got.Indices["twitter"]; !ok || len(got.Indices) != 1 {
+		t.Fatalf("Unexpected indices: %+v", got.Indices)
+	}
+}
+
+func TestClient_Recovery_Filtered(t *testing.T) {
+	filter, _ := NewIndexFilter(nil, []string{"my_awsome_*"}, true)
+
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/*,-my_awsome_*/_recovery?active_only=true&allow_no_indices=true&expand_wildcards=open%2Chidden&ignore_unavailable=true").
+		WillReturn(200, testdata.RecoveryBody)
+
+	esClient := NewClient(mockHTTPClient, WithIndexFilter(filter))
+	got, err := esClient.Recovery(context.Background(), true)
+
+	if err != nil {
+		t.Fatalf("Error on getting ES recovery state: %s", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Filtered index must be skipped, got %+v", got)
+	}
+}
+
+func TestClient_ClusterHealth_Filtered(t *testing.T) {
+	filter, _ := NewIndexFilter([]string{"other-*"}, nil, false)
+
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/_cluster/health?level=indices").WillReturn(200, testdata.ClusterHealthIndicesBody)
+
+	esClient := NewClient(mockHTTPClient, WithIndexFilter(filter))
+	got, err := esClient.ClusterHealth(context.Background(), LevelIndices)
+
+	if err != nil {
+		t.Fatalf("Error on getting ES cluster health: %s", err)
+	}
+	if len(got.Indices) != 0 {
+		t.Fatalf("Filtered index must be skipped, got %+v", got.Indices)
+	}
+	if got.ActiveShards != testdata.ClusterHealthIndices.ActiveShards {
+		t.Fatalf("Cluster level values must not be filtered")
+	}
+}
+
+func TestClient_Request_Ok(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Post("/twitter/_count").
+		WithHeader("Content-Type", "application/json").
+		WithBody(`{"query":{"match_all":{}}}`).
+		WillReturn(200, `{"count": 42}`)
+
+	esClient := NewClient(mockHTTPClient)
+
+	var got struct {
+		Count int `json:"count"`
+	}
+	err := esClient.Request(context.Background(), "POST", "/twitter/_count", strings.NewReader(`{"query":{"match_all":{}}}`), &got)
+
+	if err != nil {
+		t.Fatalf("Error on sending request: %s", err)
+	}
+	if got.Count != 42 {
+		t.Fatalf("Unexpected count: want 42, got %d", got.Count)
+	}
+}
+
+func TestClient_Request_ErrorStatus(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Get("/_plugins/missing").WillReturn(404, `{"error": "no handler found"}`)
+
+	esClient := NewClient(mockHTTPClient)
+
+	var got interface{}
+	err := esClient.Request(context.Background(), "GET", "/_plugins/missing", nil, &got)
+
+	if err == nil {
+		t.Fatalf("Error expected, got nil")
+	}
+}
+
+func TestClient_Search_Ok(t *testing.T) {
+	for _, body := range []string{testdata.SearchBody, testdata.SearchLegacyBody} {
+		mockHTTPClient := httpclient.NewClientMock()
+		mockHTTPClient.Post("/logs-*/_search").WithBody(`{"size":0}`).WillReturn(200, body)
+
+		esClient := NewClient(mockHTTPClient)
+		got, err := esClient.Search(context.Background(), "logs-*", strings.NewReader(`{"size":0}`))
+
+		if err != nil {
+			t.Fatalf("Error on search: %s", err)
+		}
+		if !reflect.DeepEqual(testdata.SearchHitsTotal, got.Hits.Total) {
+			t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.SearchHitsTotal, got.Hits.Total)
+		}
+	}
+}
+
+func TestClient_Search_Aggregations(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Post("/logs-*/_search").WillReturn(200, testdata.SearchBody)
+
+	esClient := NewClient(mockHTTPClient)
+	got, err := esClient.Search(context.Background(), "logs-*", strings.NewReader(`{}`))
+
+	if err != nil {
+		t.Fatalf("Error on search: %s", err)
+	}
+	if !reflect.DeepEqual(testdata.SearchAggregations, got.Aggregations) {
+		t.Fatalf("Structs are not equal: want %+v, got %+v", testdata.SearchAggregations, got.Aggregations)
+	}
+}
+
+func TestClient_Count_Ok(t *testing.T) {
+	mockHTTPClient := httpclient.NewClientMock()
+	mockHTTPClient.Post("/logs-*/_count").WillReturn(200, `{"count": 42, "_shards": {"total": 1}}`)
+
+	esClient := NewClient(mockHTTPClient)
+	got, err := esClient.Count(context.Background(), "logs-*", nil)
+
+	if err != nil {
+		t.Fatalf("Error on count: %s", err)
+	}
+	if got.Count != 42 {
+		t.Fatalf("Unexpected count: want 42, got %d", got.Count)
+	}
+}
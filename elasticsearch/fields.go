@@ -0,0 +1,80 @@
+package elasticsearch
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// StatsFields declares parts of a stats response used by a collector. Metrics are stats metric names
+// put into request path, e.g. "docs" and "merge" for /_stats/docs,merge. Paths are dot-separated
+// response fields relative to stats object of a single index or node, they are sent as filter_path,
+// so the rest of the response isn't serialized by ES. Zero value selects the whole response
+type StatsFields struct {
+	Metrics []string
+	Paths   []string
+}
+
+// Merge returns union of both field selections
+func (f StatsFields) Merge(other StatsFields) StatsFields {
+	return StatsFields{
+		Metrics: union(f.Metrics, other.Metrics),
+		Paths:   union(f.Paths, other.Paths),
+	}
+}
+
+// IsZero reports whether nothing is selected, so the whole response is requested
+func (f StatsFields) IsZero() bool {
+	return len(f.Metrics) == 0 && len(f.Paths) == 0
+}
+
+// selectable reports whether fields can be selected on the cluster version. Elasticsearch 1.x
+// names cache stats differently and filter_path appeared in 1.6, so whole responses are requested.
+// Unknown version may be 1.x too
+func (f StatsFields) selectable(version Version) bool {
+	return !f.IsZero() && version.Known() && version.atLeast(2, 0)
+}
+
+// metricsPath returns request path of stats API with selected metrics
+func (f StatsFields) metricsPath(path string) string {
+	if len(f.Metrics) == 0 {
+		return path
+	}
+	return path + "/" + strings.Join(f.Metrics, ",")
+}
+
+// setFilterPath adds filter_path parameter with selected paths under given per-object prefix,
+// e.g. "indices.*" or "nodes.*"
+func (f StatsFields) setFilterPath(params url.Values, prefix string) {
+	if len(f.Paths) == 0 {
+		return
+	}
+
+	filters := make([]string, len(f.Paths))
+	for i, path := range f.Paths {
+		filters[i] = prefix + "." + path
+	}
+	params.Set("filter_path", strings.Join(filters, ","))
+}
+
+// union returns sorted unique values of both lists
+func union(a, b []string) []string {
+	set := make(map[string]bool, len(a)+len(b))
+	for _, v := range a {
+		set[v] = true
+	}
+	for _, v := range b {
+		set[v] = true
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	result := make([]string, 0, len(set))
+	for v := range set {
+		result = append(result, v)
+	}
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,166 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// IndexFilter selects indices by name. Patterns are either ES wildcard expressions ("logs-*")
+// or regular expressions wrapped in slashes ("/^logs-[0-9]+$/").
+// Wildcard patterns are pushed down into ES request path when possible,
+// everything else is filtered on the exporter side.
+type IndexFilter struct {
+	include       []*indexPattern
+	exclude       []*indexPattern
+	includeHidden bool
+}
+
+type indexPattern struct {
+	raw      string
+	isRegexp bool
+	re       *regexp.Regexp
+}
+
+// NewIndexFilter returns new index filter.
+// If includeHidden is false hidden and system (dot-prefixed) indices are skipped
+// unless they are explicitly included by a pattern starting with a dot.
+func NewIndexFilter(include, exclude []string, includeHidden bool) (*IndexFilter, error) {
+	f := &IndexFilter{includeHidden: includeHidden}
+
+	for _, raw := range include {
+		p, err := newIndexPattern(raw)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, p)
+	}
+
+	for _, raw := range exclude {
+		p, err := newIndexPattern(raw)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, p)
+	}
+
+	return f, nil
+}
+
+func newIndexPattern(raw string) (*indexPattern, error) {
+	if len(raw) > 2 && strings.HasPrefix(raw, "/") && strings.HasSuffix(raw, "/") {
+		re, err := regexp.Compile(raw[1 : len(raw)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid index pattern %q: %s", raw, err)
+		}
+		return &indexPattern{raw: raw, isRegexp: true, re: re}, nil
+	}
+
+	if raw == "" || strings.ContainsAny(raw, ",/") || strings.HasPrefix(raw, "-") {
+		return nil, fmt.Errorf("invalid index pattern %q", raw)
+	}
+
+	wildcard := strings.Replace(regexp.QuoteMeta(raw), `\*`, ".*", -1)
+	return &indexPattern{raw: raw, re: regexp.MustCompile("^" + wildcard + "$")}, nil
+}
+
+// isExplicitHidden checks if pattern can only match hidden or system indices
+func (p *indexPattern) isExplicitHidden() bool {
+	if p.isRegexp {
+		return strings.HasPrefix(p.raw, `/^\.`)
+	}
+	return strings.HasPrefix(p.raw, ".")
+}
+
+// Match checks if index passes the filter
+func (f *IndexFilter) Match(index string) bool {
+	if f == nil {
+		return true
+	}
+
+	included := len(f.include) == 0
+	explicitHidden := false
+	for _, p := range f.include {
+		if p.re.MatchString(index) {
+			included = true
+			explicitHidden = explicitHidden || p.isExplicitHidden()
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, p := range f.exclude {
+		if p.re.MatchString(index) {
+			return false
+		}
+	}
+
+	return f.includeHidden || explicitHidden || !strings.HasPrefix(index, ".")
+}
+
+// expression returns ES multi-target expression which can be pushed down into request path.
+// Empty string means that all indices are requested.
+func (f *IndexFilter) expression() string {
+	if f == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, p := range f.include {
+		if p.isRegexp {
+			// regexp can't be expressed in ES syntax, so all indices have to be requested
+			parts = nil
+			break
+		}
+		parts = append(parts, p.raw)
+	}
+
+	var excludes []string
+	for _, p := range f.exclude {
+		if !p.isRegexp {
+			excludes = append(excludes, "-"+p.raw)
+		}
+	}
+
+	if len(excludes) > 0 && len(parts) == 0 {
+		// exclusions are allowed only after a wildcard expression
+		parts = []string{"*"}
+	}
+
+	return strings.Join(append(parts, excludes...), ",")
+}
+
+// expandWildcards returns value for "expand_wildcards" request parameter.
+// Hidden indices exist since 7.7, older versions fail on "hidden" value
+func (f *IndexFilter) expandWildcards(version Version) string {
+	if f.includeHidden && version.atLeast(7, 7) {
+		return "open,hidden"
+	}
+	return "open"
+}
+
+// indicesPath builds request path for multi-index API with the filter pushed down
+func (f *IndexFilter) indicesPath(version Version, api string, params url.Values) string {
+	if f == nil {
+		if len(params) == 0 {
+			return "/" + api
+		}
+		return "/" + api + "?" + params.Encode()
+	}
+
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("expand_wildcards", f.expandWildcards(version))
+
+	path := "/" + api
+	if expr := f.expression(); expr != "" {
+		path = "/" + expr + path
+		params.Set("ignore_unavailable", "true")
+		params.Set("allow_no_indices", "true")
+	}
+
+	return path + "?" + params.Encode()
+}
@@ -0,0 +1,84 @@
+package elasticsearch
+
+import (
+	"testing"
+)
+
+func TestIndexFilter_Match(t *testing.T) {
+	filter, err := NewIndexFilter(
+		[]string{"logs-*", `/^metrics-[0-9]+$/`, ".kibana"},
+		[]string{"logs-debug-*", `/-tmp$/`},
+		false,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	cases := map[string]bool{
+		"logs-app":       true,
+		"logs-debug-app": false,
+		"logs-app-tmp":   false,
+		"metrics-42":     true,
+		"metrics-app":    false,
+		".kibana":        true,
+		".security":      false,
+		"other":          false,
+	}
+
+	for index, want := range cases {
+		if got := filter.Match(index); got != want {
+			t.Fatalf("Unexpected match result for %q: want %t, got %t", index, want, got)
+		}
+	}
+}
+
+func TestIndexFilter_MatchHidden(t *testing.T) {
+	filter, err := NewIndexFilter(nil, nil, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if !filter.Match("twitter") {
+		t.Fatalf("Regular index must match")
+	}
+	if filter.Match(".monitoring-es-7") {
+		t.Fatalf("Hidden index must not match")
+	}
+
+	var nilFilter *IndexFilter
+	if !nilFilter.Match(".monitoring-es-7") {
+		t.Fatalf("Nil filter must match any index")
+	}
+}
+
+func TestIndexFilter_Invalid(t *testing.T) {
+	for _, pattern := range []string{"", "-logs", "a,b", "/[/"} {
+		if _, err := NewIndexFilter([]string{pattern}, nil, true); err == nil {
+			t.Fatalf("Error expected for pattern %q, got nil", pattern)
+		}
+	}
+}
+
+func TestIndexFilter_Expression(t *testing.T) {
+	cases := []struct {
+		include, exclude []string
+		want             string
+	}{
+		{nil, nil, ""},
+		{[]string{"logs-*", "metrics"}, nil, "logs-*,metrics"},
+		{[]string{"logs-*"}, []string{"logs-debug-*", `/-tmp$/`}, "logs-*,-logs-debug-*"},
+		{nil, []string{".monitoring-*"}, "*,-.monitoring-*"},
+		{[]string{"logs-*", `/^metrics-[0-9]+$/`}, []string{"logs-debug-*"}, "*,-logs-debug-*"},
+		{[]string{`/^metrics-[0-9]+$/`}, nil, ""},
+	}
+
+	for _, c := range cases {
+		filter, err := NewIndexFilter(c.include, c.exclude, true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if got := filter.expression(); got != c.want {
+			t.Fatalf("Unexpected expression for %v/%v: want %q, got %q", c.include, c.exclude, c.want, got)
+		}
+	}
+}
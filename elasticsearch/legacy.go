@@ -0,0 +1,41 @@
+package elasticsearch
+
+import (
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
+// Elasticsearch 1.x names caches differently: shard request cache is called "query_cache"
+// and node query cache is called "filter_cache". Responses of 1.x are moved to 2.x+ fields,
+// so the same metric always means the same cache.
+
+// upgradeIndices1x moves 1.x query cache stats of indices to request cache stats
+func upgradeIndices1x(v *model.Indices) {
+	upgradeIndex1x(&v.All)
+	for name, index := range v.Indices {
+		upgradeIndex1x(&index)
+		v.Indices[name] = index
+	}
+}
+
+func upgradeIndex1x(index *model.Index) {
+	for _, summary := range []*model.IndexSummary{&index.Primaries, &index.Total} {
+		summary.RequestCache.MemorySizeInBytes = summary.QueryCache.MemorySizeInBytes
+		summary.RequestCache.Evictions = summary.QueryCache.Evictions
+		summary.RequestCache.HitCount = summary.QueryCache.HitCount
+		summary.RequestCache.MissCount = summary.QueryCache.MissCount
+		summary.QueryCache = model.IndexSummary{}.QueryCache
+	}
+}
+
+// upgradeNodes1x moves 1.x query cache stats of nodes to request cache stats
+func upgradeNodes1x(v *model.Nodes) {
+	for id, node := range v.Nodes {
+		upgradeNode1x(&node)
+		v.Nodes[id] = node
+	}
+}
+
+func upgradeNode1x(node *model.Node) {
+	node.Indices.RequestCache = node.Indices.QueryCache
+	node.Indices.QueryCache = model.NodeIndicesCache{}
+}
@@ -0,0 +1,82 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
+// Elasticsearch ILM and OpenSearch ISM explain responses are decoded to the same model,
+// ISM state is exported as lifecycle phase.
+
+// ilmErrorStep is a step name ILM sets when a step of the policy failed
+const ilmErrorStep = "ERROR"
+
+// ilmExplain returns lifecycle state of indices managed by Elasticsearch ILM
+func (c *ESClient) ilmExplain(ctx context.Context) (model.Lifecycle, error) {
+	var v model.ILMExplain
+	if err := c.makeRequest(ctx, "/*/_ilm/explain", &v); err != nil {
+		return nil, err
+	}
+
+	result := make(model.Lifecycle, len(v.Indices))
+	for name, index := range v.Indices {
+		if !index.Managed {
+			continue
+		}
+		result[name] = model.IndexLifecycle{
+			Policy:             index.Policy,
+			Phase:              index.Phase,
+			Action:             index.Action,
+			Step:               index.Step,
+			Failed:             index.Step == ilmErrorStep,
+			Retries:            index.FailedStepRetryCount,
+			PhaseTimeInMillis:  index.PhaseTimeMillis,
+			ActionTimeInMillis: index.ActionTimeMillis,
+			StepTimeInMillis:   index.StepTimeMillis,
+		}
+	}
+
+	return result, nil
+}
+
+// ismExplain returns lifecycle state of indices managed by OpenSearch ISM
+func (c *ESClient) ismExplain(ctx context.Context) (model.Lifecycle, error) {
+	var v map[string]json.RawMessage
+	if err := c.makeRequest(ctx, "/_plugins/_ism/explain/*", &v); err != nil {
+		return nil, err
+	}
+
+	result := make(model.Lifecycle, len(v))
+	for name, raw := range v {
+		var index model.ISMExplainIndex
+		// "total_managed_indices" and other non-index keys aren't objects
+		if err := json.Unmarshal(raw, &index); err != nil || index.PolicyID == "" {
+			continue
+		}
+
+		lifecycle := model.IndexLifecycle{Policy: index.PolicyID}
+		if index.State != nil {
+			lifecycle.Phase = index.State.Name
+			lifecycle.PhaseTimeInMillis = index.State.StartTime
+		}
+		if index.Action != nil {
+			lifecycle.Action = index.Action.Name
+			lifecycle.ActionTimeInMillis = index.Action.StartTime
+			lifecycle.Failed = index.Action.Failed
+			lifecycle.Retries = index.Action.ConsumedRetries
+		}
+		if index.Step != nil {
+			lifecycle.Step = index.Step.Name
+			lifecycle.StepTimeInMillis = index.Step.StartTime
+		}
+		if index.RetryInfo != nil && index.RetryInfo.Failed {
+			lifecycle.Failed = true
+			lifecycle.Retries = index.RetryInfo.ConsumedRetries
+		}
+		result[name] = lifecycle
+	}
+
+	return result, nil
+}
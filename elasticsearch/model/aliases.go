@@ -1,9 +1,47 @@
 package model
 
+import (
+	"encoding/json"
+)
+
 // Aliases is a representation of ElasticSearch index aliases
 type Aliases map[string]AliasInfo
 
-// AliasInfo is a representation of ElasticSearch index alias
+// AliasInfo is a representation of ElasticSearch index aliases
 type AliasInfo struct {
-	Aliases map[string]interface{} `json:"aliases"`
+	Aliases map[string]Alias `json:"aliases"`
+}
+
+// Alias is a representation of ElasticSearch index alias definition
+type Alias struct {
+	Filter        json.RawMessage `json:"filter,omitempty"`
+	IndexRouting  string          `json:"index_routing,omitempty"`
+	SearchRouting string          `json:"search_routing,omitempty"`
+	// IsWriteIndex is nil if the flag is not set explicitly
+	IsWriteIndex *bool `json:"is_write_index,omitempty"`
+	IsHidden     *bool `json:"is_hidden,omitempty"`
+}
+
+// WriteIndices returns write index name per alias. If alias points to a single index
+// without explicit is_write_index flag, the index is the write index.
+func (a Aliases) WriteIndices() map[string]string {
+	result := make(map[string]string)
+	indices := make(map[string][]string)
+
+	for indexName, info := range a {
+		for aliasName, alias := range info.Aliases {
+			indices[aliasName] = append(indices[aliasName], indexName)
+			if alias.IsWriteIndex != nil && *alias.IsWriteIndex {
+				result[aliasName] = indexName
+			}
+		}
+	}
+
+	for aliasName, names := range indices {
+		if len(names) == 1 && a[names[0]].Aliases[aliasName].IsWriteIndex == nil {
+			result[aliasName] = names[0]
+		}
+	}
+
+	return result
 }
@@ -31,4 +31,17 @@ type ClusterHealthIndex struct {
 	RelocatingShards    int    `json:"relocating_shards"`
 	InitializingShards  int    `json:"initializing_shards"`
 	UnassignedShards    int    `json:"unassigned_shards"`
+
+	// Shards are reported only for "shards" level
+	Shards map[string]ClusterHealthShard `json:"shards"`
+}
+
+// ClusterHealthShard represents ES shard health, key is a shard number
+type ClusterHealthShard struct {
+	Status             string `json:"status"`
+	PrimaryActive      bool   `json:"primary_active"`
+	ActiveShards       int    `json:"active_shards"`
+	RelocatingShards   int    `json:"relocating_shards"`
+	InitializingShards int    `json:"initializing_shards"`
+	UnassignedShards   int    `json:"unassigned_shards"`
 }
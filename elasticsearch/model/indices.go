@@ -82,6 +82,7 @@ type IndexSummary struct {
 	} `json:"refresh"`
 	Flush struct {
 		Total             int64 `json:"total"`
+		Periodic          int64 `json:"periodic"`
 		TotalTimeInMillis int64 `json:"total_time_in_millis"`
 	} `json:"flush"`
 	Warmer struct {
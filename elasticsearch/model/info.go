@@ -0,0 +1,22 @@
+package model
+
+// Info is a representation of ES root endpoint (GET /) response
+type Info struct {
+	Name        string      `json:"name"`
+	ClusterName string      `json:"cluster_name"`
+	ClusterUUID string      `json:"cluster_uuid"`
+	Version     InfoVersion `json:"version"`
+	Tagline     string      `json:"tagline"`
+}
+
+// InfoVersion is a version and build info of ES node.
+// Distribution is set by OpenSearch only
+type InfoVersion struct {
+	Number        string `json:"number"`
+	Distribution  string `json:"distribution"`
+	BuildFlavor   string `json:"build_flavor"`
+	BuildType     string `json:"build_type"`
+	BuildHash     string `json:"build_hash"`
+	BuildSnapshot bool   `json:"build_snapshot"`
+	LuceneVersion string `json:"lucene_version"`
+}
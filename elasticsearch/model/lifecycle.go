@@ -0,0 +1,66 @@
+package model
+
+// Lifecycle is a lifecycle state of managed indices, either from Elasticsearch ILM or OpenSearch ISM
+type Lifecycle map[string]IndexLifecycle
+
+// IndexLifecycle is a lifecycle state of an index.
+// Phase is ILM phase or ISM state, timestamps are zero if unknown
+type IndexLifecycle struct {
+	Policy             string
+	Phase              string
+	Action             string
+	Step               string
+	Failed             bool
+	Retries            int64
+	PhaseTimeInMillis  int64
+	ActionTimeInMillis int64
+	StepTimeInMillis   int64
+}
+
+// ILMExplain is a representation of Elasticsearch /<index>/_ilm/explain response
+type ILMExplain struct {
+	Indices map[string]ILMExplainIndex `json:"indices"`
+}
+
+// ILMExplainIndex is an ILM state of an index
+type ILMExplainIndex struct {
+	Index                string `json:"index"`
+	Managed              bool   `json:"managed"`
+	Policy               string `json:"policy"`
+	LifecycleDateMillis  int64  `json:"lifecycle_date_millis"`
+	Phase                string `json:"phase"`
+	PhaseTimeMillis      int64  `json:"phase_time_millis"`
+	Action               string `json:"action"`
+	ActionTimeMillis     int64  `json:"action_time_millis"`
+	Step                 string `json:"step"`
+	StepTimeMillis       int64  `json:"step_time_millis"`
+	FailedStep           string `json:"failed_step"`
+	FailedStepRetryCount int64  `json:"failed_step_retry_count"`
+}
+
+// ISMExplainIndex is an OpenSearch ISM state of an index from /_plugins/_ism/explain response.
+// The response is an object with index names as keys and "total_managed_indices" number
+type ISMExplainIndex struct {
+	Index    string `json:"index"`
+	PolicyID string `json:"policy_id"`
+	Enabled  *bool  `json:"enabled"`
+	State    *struct {
+		Name      string `json:"name"`
+		StartTime int64  `json:"start_time"`
+	} `json:"state"`
+	Action *struct {
+		Name            string `json:"name"`
+		StartTime       int64  `json:"start_time"`
+		Failed          bool   `json:"failed"`
+		ConsumedRetries int64  `json:"consumed_retries"`
+	} `json:"action"`
+	Step *struct {
+		Name       string `json:"name"`
+		StartTime  int64  `json:"start_time"`
+		StepStatus string `json:"step_status"`
+	} `json:"step"`
+	RetryInfo *struct {
+		Failed          bool  `json:"failed"`
+		ConsumedRetries int64 `json:"consumed_retries"`
+	} `json:"retry_info"`
+}
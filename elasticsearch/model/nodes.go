@@ -282,8 +282,9 @@ type NodeHTTP struct {
 
 // FS is a representation of file system information, data path, free disk space, read/write stats
 type FS struct {
-	Timestamp int64        `json:"timestamp"`
-	Data      []NodeFSData `json:"data"`
+	Timestamp int64         `json:"timestamp"`
+	Data      []NodeFSData  `json:"data"`
+	IOStats   NodeFSIOStats `json:"io_stats"`
 }
 
 // NodeFSData is a representation of filesystem stats
@@ -300,9 +301,19 @@ type NodeFSData struct {
 	DiskWriteSize int64  `json:"disk_write_size_in_bytes"`
 }
 
-// Tasks is a representation of ElasticSearch cluster tasks statistics
-type Tasks []struct {
-	Action      string `json:"action"`
-	RunningTime string `json:"running_time"`
-	Node        string `json:"node"`
+// NodeFSIOStats is a representation of Linux block devices I/O stats
+type NodeFSIOStats struct {
+	Devices []NodeFSIOStatsDevice `json:"devices"`
+	Total   NodeFSIOStatsDevice   `json:"total"`
+}
+
+// NodeFSIOStatsDevice is a representation of I/O stats of a single block device
+type NodeFSIOStatsDevice struct {
+	DeviceName      string `json:"device_name"`
+	Operations      int64  `json:"operations"`
+	ReadOperations  int64  `json:"read_operations"`
+	WriteOperations int64  `json:"write_operations"`
+	ReadKilobytes   int64  `json:"read_kilobytes"`
+	WriteKilobytes  int64  `json:"write_kilobytes"`
+	IOTimeInMillis  int64  `json:"io_time_in_millis"`
 }
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+)
+
+// SearchResponse is a representation of ES _search response
+type SearchResponse struct {
+	Took     int64 `json:"took"`
+	TimedOut bool  `json:"timed_out"`
+	Shards   struct {
+		Total      int `json:"total"`
+		Successful int `json:"successful"`
+		Skipped    int `json:"skipped"`
+		Failed     int `json:"failed"`
+	} `json:"_shards"`
+	Hits struct {
+		Total SearchHitsTotal `json:"total"`
+		Hits  []SearchHit     `json:"hits"`
+	} `json:"hits"`
+	Aggregations map[string]interface{} `json:"aggregations"`
+}
+
+// SearchHit is a representation of single search hit
+type SearchHit struct {
+	Index  string          `json:"_index"`
+	ID     string          `json:"_id"`
+	Source json.RawMessage `json:"_source"`
+}
+
+// SearchHitsTotal is a total number of search hits.
+// ES prior to 7.0 returns it as a number, later versions return an object.
+type SearchHitsTotal struct {
+	Value    int64  `json:"value"`
+	Relation string `json:"relation"`
+}
+
+// UnmarshalJSON decodes both number and object representations
+func (t *SearchHitsTotal) UnmarshalJSON(data []byte) error {
+	var value int64
+	if err := json.Unmarshal(data, &value); err == nil {
+		t.Value = value
+		t.Relation = "eq"
+		return nil
+	}
+
+	type plain SearchHitsTotal
+	return json.Unmarshal(data, (*plain)(t))
+}
+
+// CountResponse is a representation of ES _count response
+type CountResponse struct {
+	Count int64 `json:"count"`
+}
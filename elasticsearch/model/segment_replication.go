@@ -0,0 +1,29 @@
+package model
+
+import "strings"
+
+// SegmentReplication is a representation of OpenSearch /_cat/segment_replication response
+type SegmentReplication []SegmentReplicationShard
+
+// SegmentReplicationShard is a segment replication state of a replica shard.
+// Values are strings as in all cat APIs, bytes and times are requested in b and ms units
+type SegmentReplicationShard struct {
+	ShardID           string `json:"shardId"`
+	TargetNode        string `json:"target_node"`
+	TargetHost        string `json:"target_host"`
+	CheckpointsBehind string `json:"checkpoints_behind"`
+	BytesBehind       string `json:"bytes_behind"`
+	CurrentLag        string `json:"current_lag"`
+	LastCompletedLag  string `json:"last_completed_lag"`
+	RejectedRequests  string `json:"rejected_requests"`
+}
+
+// Shard returns index name and shard number parsed from shard ID like "[logs-000001][0]"
+func (s SegmentReplicationShard) Shard() (string, string) {
+	id := strings.TrimSuffix(strings.TrimPrefix(s.ShardID, "["), "]")
+	parts := strings.SplitN(id, "][", 2)
+	if len(parts) != 2 {
+		return id, ""
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,27 @@
+package model
+
+// Tasks is a representation of ES _tasks response grouped by nodes
+type Tasks struct {
+	Nodes map[string]TasksNode `json:"nodes"`
+}
+
+// TasksNode is a representation of node with tasks running on it
+type TasksNode struct {
+	Name  string          `json:"name"`
+	Host  string          `json:"host"`
+	Tasks map[string]Task `json:"tasks"`
+}
+
+// Task is a representation of single running task
+type Task struct {
+	Node               string `json:"node"`
+	ID                 int64  `json:"id"`
+	Type               string `json:"type"`
+	Action             string `json:"action"`
+	Description        string `json:"description"`
+	StartTimeInMillis  int64  `json:"start_time_in_millis"`
+	RunningTimeInNanos int64  `json:"running_time_in_nanos"`
+	Cancellable        bool   `json:"cancellable"`
+	Cancelled          bool   `json:"cancelled"`
+	ParentTaskID       string `json:"parent_task_id"`
+}
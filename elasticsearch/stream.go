@@ -0,0 +1,153 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
+// Stats responses of big clusters are hundreds of megabytes. Streaming methods read them
+// with json.Decoder token API and decode one index or node at a time, so neither
+// the response body nor the whole decoded structure is held in memory.
+
+// IndicesStream reads /_stats response with given fields and calls fn for each index in order of the response.
+// Indices skipped by the index filter aren't decoded
+func (c *ESClient) IndicesStream(ctx context.Context, fields StatsFields, fn func(name string, index model.Index)) error {
+	version := c.Version()
+
+	api, params := "_stats", url.Values{}
+	if fields.selectable(version) {
+		api = fields.metricsPath(api)
+		fields.setFilterPath(params, "indices.*")
+	}
+
+	return c.stream(ctx, c.indexFilter.indicesPath(version, api, params), func(dec *json.Decoder) error {
+		return decodeObject(dec, func(key string) error {
+			if key != "indices" {
+				return skipValue(dec)
+			}
+
+			return decodeObject(dec, func(name string) error {
+				if !c.indexFilter.Match(name) {
+					return skipValue(dec)
+				}
+
+				var index model.Index
+				if err := dec.Decode(&index); err != nil {
+					return err
+				}
+				if !version.atLeast(2, 0) {
+					upgradeIndex1x(&index)
+				}
+
+				fn(name, index)
+				return nil
+			})
+		})
+	})
+}
+
+// NodesStream reads nodes stats response with given fields and calls fn for each node in order of the response
+func (c *ESClient) NodesStream(ctx context.Context, fetchAllNodesInfo bool, fields StatsFields, fn func(node model.Node)) error {
+	version := c.Version()
+
+	path := nodesPath(fetchAllNodesInfo)
+	if fields.selectable(version) {
+		params := url.Values{}
+		fields.setFilterPath(params, "nodes.*")
+		path = fields.metricsPath(path)
+		if len(params) > 0 {
+			path += "?" + params.Encode()
+		}
+	}
+
+	return c.stream(ctx, path, func(dec *json.Decoder) error {
+		return decodeObject(dec, func(key string) error {
+			if key != "nodes" {
+				return skipValue(dec)
+			}
+
+			return decodeObject(dec, func(string) error {
+				var node model.Node
+				if err := dec.Decode(&node); err != nil {
+					return err
+				}
+				if !version.atLeast(2, 0) {
+					upgradeNode1x(&node)
+				}
+
+				fn(node)
+				return nil
+			})
+		})
+	})
+}
+
+// stream sends GET request and passes JSON decoder of response body to decode
+func (c *ESClient) stream(ctx context.Context, path string, decode func(*json.Decoder) error) error {
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.send(req.WithContext(ctx), decode)
+}
+
+// decodeObject reads JSON object and calls field for each key with decoder positioned at its value.
+// field must consume the value
+func decodeObject(dec *json.Decoder, field func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("unexpected object key %v", token)
+		}
+		if err := field(key); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// skipValue reads and drops the next JSON value
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
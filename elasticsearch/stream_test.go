@@ -0,0 +1,306 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/testdata"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+)
+
+// bodyClient returns http client responding with given body to any stats request
+func bodyClient(body []byte) httpclient.Client {
+	return httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/" {
+			body := []byte(testdata.InfoBody)
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+	})
+}
+
+func TestClient_IndicesStream(t *testing.T) {
+	body := []byte(testdata.Indices8Body)
+	want, err := NewClient(bodyClient(body)).Indices(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]model.Index)
+	err = NewClient(bodyClient(body)).IndicesStream(context.Background(), StatsFields{}, func(name string, index model.Index) {
+		got[name] = index
+	})
+
+	if err != nil {
+		t.Fatalf("Error on streaming ES indices stats: %s", err)
+	}
+	if !reflect.DeepEqual(want.Indices, got) {
+		t.Fatalf("Streamed indices are not equal to decoded ones: want %+v, got %+v", want.Indices, got)
+	}
+}
+
+func TestClient_IndicesStream_Filtered(t *testing.T) {
+	filter, _ := NewIndexFilter(nil, []string{`/^logs-/`}, true)
+
+	var got []string
+	err := NewClient(bodyClient([]byte(testdata.Indices8Body)), WithIndexFilter(filter)).IndicesStream(context.Background(), StatsFields{}, func(name string, index model.Index) {
+		got = append(got, name)
+	})
+
+	if err != nil {
+		t.Fatalf("Error on streaming ES indices stats: %s", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Filtered indices must be skipped, got %v", got)
+	}
+}
+
+func TestClient_NodesStream(t *testing.T) {
+	body := []byte(testdata.NodesBody)
+	want, err := NewClient(bodyClient(body)).Nodes(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []model.Node
+	err = NewClient(bodyClient(body)).NodesStream(context.Background(), true, StatsFields{}, func(node model.Node) {
+		got = append(got, node)
+	})
+
+	if err != nil {
+		t.Fatalf("Error on streaming ES nodes stats: %s", err)
+	}
+	if len(got) != len(want.Nodes) {
+		t.Fatalf("Unexpected nodes count, want %d, got %d", len(want.Nodes), len(got))
+	}
+	for _, node := range got {
+		if !reflect.DeepEqual(want.Nodes[nodeID(t, want, node)], node) {
+			t.Fatalf("Streamed node %s is not equal to decoded one", node.Name)
+		}
+	}
+}
+
+func TestClient_Stream_Malformed(t *testing.T) {
+	for _, body := range []string{`[]`, `{"indices": {"twitter": {}`, `{"indices": {"twitter": []}}`} {
+		err := NewClient(bodyClient([]byte(body))).IndicesStream(context.Background(), StatsFields{}, func(string, model.Index) {})
+		if err == nil {
+			t.Fatalf("Error expected for %s", body)
+		}
+	}
+}
+
+func TestClient_Stream_Fields(t *testing.T) {
+	fields := StatsFields{Metrics: []string{"docs", "merge"}, Paths: []string{"primaries.docs", "total.merges"}}
+	filter, _ := NewIndexFilter([]string{"twitter*"}, nil, false)
+
+	tests := map[string]struct {
+		info      string
+		fields    StatsFields
+		indexPath string
+		nodePath  string
+	}{
+		"selected": {
+			info:      testdata.InfoBody,
+			fields:    fields,
+			indexPath: "/twitter*/_stats/docs,merge?allow_no_indices=true&expand_wildcards=open&filter_path=indices.%2A.primaries.docs%2Cindices.%2A.total.merges&ignore_unavailable=true",
+			nodePath:  "/_nodes/stats/docs,merge?filter_path=nodes.%2A.primaries.docs%2Cnodes.%2A.total.merges",
+		},
+		"zero": {
+			info:      testdata.InfoBody,
+			indexPath: "/twitter*/_stats?allow_no_indices=true&expand_wildcards=open&ignore_unavailable=true",
+			nodePath:  "/_nodes/stats",
+		},
+		"1.x": {
+			info:      testdata.Info1xBody,
+			fields:    fields,
+			indexPath: "/twitter*/_stats?allow_no_indices=true&expand_wildcards=open&ignore_unavailable=true",
+			nodePath:  "/_nodes/stats",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockHTTPClient := httpclient.NewClientMock()
+			mockHTTPClient.Get("/").WillReturn(200, test.info)
+			mockHTTPClient.Get(test.indexPath).WillReturn(200, `{"indices": {}}`)
+			mockHTTPClient.Get(test.nodePath).WillReturn(200, `{"nodes": {}}`)
+
+			esClient := NewClient(mockHTTPClient, WithIndexFilter(filter))
+			if err := esClient.IndicesStream(context.Background(), test.fields, func(string, model.Index) {}); err != nil {
+				t.Fatalf("Error on streaming ES indices stats: %s", err)
+			}
+			if err := esClient.NodesStream(context.Background(), true, test.fields, func(model.Node) {}); err != nil {
+				t.Fatalf("Error on streaming ES nodes stats: %s", err)
+			}
+		})
+	}
+}
+
+func TestStatsFields_Merge(t *testing.T) {
+	got := StatsFields{Metrics: []string{"store", "docs"}}.Merge(StatsFields{Metrics: []string{"docs"}, Paths: []string{"name"}})
+
+	want := StatsFields{Metrics: []string{"docs", "store"}, Paths: []string{"name"}}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("Unexpected merged fields: want %+v, got %+v", want, got)
+	}
+}
+
+func nodeID(t *testing.T, nodes *model.Nodes, node model.Node) string {
+	for id, n := range nodes.Nodes {
+		if n.Name == node.Name {
+			return id
+		}
+	}
+	t.Fatalf("Unexpected node %s", node.Name)
+	return ""
+}
+
+// syntheticIndices returns /_stats response with given number of indices with all stats fields set
+func syntheticIndices(count int) []byte {
+	index, _ := json.Marshal(model.Index{})
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"_shards": {"total": 1, "successful": 1, "failed": 0}, "_all": `)
+	buf.Write(index)
+	buf.WriteString(`, "indices": {`)
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `"logs-app-%06d": `, i)
+		buf.Write(index)
+	}
+	buf.WriteString(`}}`)
+
+	return buf.Bytes()
+}
+
+// syntheticNodes returns nodes stats response with given number of copies of test node
+func syntheticNodes(count int) []byte {
+	var nodes struct {
+		Nodes map[string]json.RawMessage `json:"nodes"`
+	}
+	json.Unmarshal([]byte(testdata.NodesBody), &nodes)
+
+	var node json.RawMessage
+	for _, n := range nodes.Nodes {
+		node = n
+		break
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"cluster_name": "synthetic", "nodes": {`)
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `"node-%06d": `, i)
+		buf.Write(node)
+	}
+	buf.WriteString(`}}`)
+
+	return buf.Bytes()
+}
+
+// heapPeak tracks the largest heap size above baseline. Heap is sampled while decoded data is held
+type heapPeak struct {
+	base uint64
+	peak uint64
+}
+
+func newHeapPeak() *heapPeak {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return &heapPeak{base: m.HeapAlloc}
+}
+
+func (h *heapPeak) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	if m.HeapAlloc > h.base && m.HeapAlloc-h.base > h.peak {
+		h.peak = m.HeapAlloc - h.base
+	}
+}
+
+// Run with -benchtime=1x, each operation decodes about 30 MB response:
+//   go test ./elasticsearch -run=^$ -bench=Benchmark -benchtime=1x
+// peak-heap-MB shows heap in use while the stats are decoded.
+
+func BenchmarkClient_Indices(b *testing.B) {
+	esClient := NewClient(bodyClient(syntheticIndices(10000)))
+	b.ResetTimer()
+
+	heap := newHeapPeak()
+	for n := 0; n < b.N; n++ {
+		v, err := esClient.Indices(context.Background())
+		if err != nil {
+			b.Fatal(err)
+		}
+		heap.sample()
+		runtime.KeepAlive(v)
+	}
+	b.ReportMetric(float64(heap.peak)/(1<<20), "peak-heap-MB")
+}
+
+func BenchmarkClient_IndicesStream(b *testing.B) {
+	esClient := NewClient(bodyClient(syntheticIndices(10000)))
+	b.ResetTimer()
+
+	heap := newHeapPeak()
+	for n := 0; n < b.N; n++ {
+		count := 0
+		err := esClient.IndicesStream(context.Background(), StatsFields{}, func(name string, index model.Index) {
+			if count++; count%1000 == 0 {
+				heap.sample()
+			}
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(heap.peak)/(1<<20), "peak-heap-MB")
+}
+
+func BenchmarkClient_Nodes(b *testing.B) {
+	esClient := NewClient(bodyClient(syntheticNodes(2000)))
+	b.ResetTimer()
+
+	heap := newHeapPeak()
+	for n := 0; n < b.N; n++ {
+		v, err := esClient.Nodes(context.Background(), true)
+		if err != nil {
+			b.Fatal(err)
+		}
+		heap.sample()
+		runtime.KeepAlive(v)
+	}
+	b.ReportMetric(float64(heap.peak)/(1<<20), "peak-heap-MB")
+}
+
+func BenchmarkClient_NodesStream(b *testing.B) {
+	esClient := NewClient(bodyClient(syntheticNodes(2000)))
+	b.ResetTimer()
+
+	heap := newHeapPeak()
+	for n := 0; n < b.N; n++ {
+		count := 0
+		err := esClient.NodesStream(context.Background(), true, StatsFields{}, func(node model.Node) {
+			if count++; count%100 == 0 {
+				heap.sample()
+			}
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(heap.peak)/(1<<20), "peak-heap-MB")
+}
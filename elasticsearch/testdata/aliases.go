@@ -1,19 +1,47 @@
 package testdata
 
 import (
+	"encoding/json"
+
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
 )
 
 // Test data for aliases info
 var (
-	AliasesBody = `{"twitter": {"aliases": {"alias1": {}, "alias2": {}}}}`
+	AliasesBody = `
+{
+	"twitter": {"aliases": {"alias1": {}, "alias2": {"filter": {"term": {"user": "kimchy"}}, "index_routing": "1", "search_routing": "1,2"}}},
+	"logs-000001": {"aliases": {"logs": {"is_write_index": false}}},
+	"logs-000002": {"aliases": {"logs": {"is_write_index": true}}}
+}`
 
 	Aliases = model.Aliases{
 		"twitter": model.AliasInfo{
-			Aliases: map[string]interface{}{
-				"alias1": map[string]interface{}{},
-				"alias2": map[string]interface{}{},
+			Aliases: map[string]model.Alias{
+				"alias1": {},
+				"alias2": {
+					Filter:        json.RawMessage(`{"term": {"user": "kimchy"}}`),
+					IndexRouting:  "1",
+					SearchRouting: "1,2",
+				},
 			},
 		},
+		"logs-000001": model.AliasInfo{
+			Aliases: map[string]model.Alias{"logs": {IsWriteIndex: &False}},
+		},
+		"logs-000002": model.AliasInfo{
+			Aliases: map[string]model.Alias{"logs": {IsWriteIndex: &True}},
+		},
 	}
+
+	// AliasesWriteIndices are write indices of Aliases
+	AliasesWriteIndices = map[string]string{
+		"alias1": "twitter",
+		"alias2": "twitter",
+		"logs":   "logs-000002",
+	}
+
+	// True and False are addressable bool values for optional fields
+	True  = true
+	False = false
 )
@@ -0,0 +1,85 @@
+package testdata
+
+import (
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
+// Test data for shard level cluster health info
+var (
+	ClusterHealthShardsBody = `
+{
+	"cluster_name": "my-huge-cluster",
+	"status": "yellow",
+	"timed_out": false,
+	"number_of_nodes": 3,
+	"number_of_data_nodes": 3,
+	"active_primary_shards": 2,
+	"active_shards": 3,
+	"relocating_shards": 0,
+	"initializing_shards": 0,
+	"unassigned_shards": 1,
+	"delayed_unassigned_shards": 0,
+	"number_of_pending_tasks": 2,
+	"number_of_in_flight_fetch": 0,
+	"task_max_waiting_in_queue_millis": 1500,
+	"active_shards_percent_as_number": 75.0,
+	"indices": {
+		"some-index": {
+			"status": "yellow",
+			"number_of_shards": 2,
+			"number_of_replicas": 1,
+			"active_primary_shards": 2,
+			"active_shards": 3,
+			"relocating_shards": 0,
+			"initializing_shards": 0,
+			"unassigned_shards": 1,
+			"shards": {
+				"0": {
+					"status": "green",
+					"primary_active": true,
+					"active_shards": 2,
+					"relocating_shards": 0,
+					"initializing_shards": 0,
+					"unassigned_shards": 0
+				},
+				"1": {
+					"status": "yellow",
+					"primary_active": true,
+					"active_shards": 1,
+					"relocating_shards": 0,
+					"initializing_shards": 0,
+					"unassigned_shards": 1
+				}
+			}
+		}
+	}
+}
+`
+
+	ClusterHealthShards = &model.ClusterHealth{
+		ClusterName:                 "my-huge-cluster",
+		Status:                      "yellow",
+		NumberOfNodes:               3,
+		NumberOfDataNodes:           3,
+		ActivePrimaryShards:         2,
+		ActiveShards:                3,
+		UnassignedShards:            1,
+		NumberOfPendingTasks:        2,
+		TaskMaxWaitingInQueueMillis: 1500,
+		ActiveShardsPercentAsNumber: 75.0,
+		Indices: map[string]model.ClusterHealthIndex{
+			"some-index": {
+				Status:              "yellow",
+				NumberOfShards:      2,
+				NumberOfReplicas:    1,
+				ActivePrimaryShards: 2,
+				ActiveShards:        3,
+				UnassignedShards:    1,
+				Shards: map[string]model.ClusterHealthShard{
+					"0": {Status: "green", PrimaryActive: true, ActiveShards: 2},
+					"1": {Status: "yellow", PrimaryActive: true, ActiveShards: 1, UnassignedShards: 1},
+				},
+			},
+		},
+	}
+)
@@ -0,0 +1,139 @@
+package testdata
+
+import "github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+
+// Test data for Elasticsearch 8.x responses. Compat bodies are responses to requests
+// with "compatible-with=7" headers, where removed mapping types are added back
+var (
+	Info8Body = `
+{
+	"name": "es8-node-1",
+	"cluster_name": "my-cluster-8",
+	"cluster_uuid": "r5dNM3ovQ1a8AJYUHhGDvg",
+	"version": {
+		"number": "8.11.1",
+		"build_flavor": "default",
+		"build_type": "docker",
+		"build_hash": "6f9ff581fbcde658e6f69d6ce03050f060d1fd0c",
+		"build_date": "2023-11-11T10:05:59.421038163Z",
+		"build_snapshot": false,
+		"lucene_version": "9.8.0",
+		"minimum_wire_compatibility_version": "7.17.0",
+		"minimum_index_compatibility_version": "7.0.0"
+	},
+	"tagline": "You Know, for Search"
+}`
+
+	ClusterHealth8Body = `
+{
+	"cluster_name": "my-cluster-8",
+	"status": "yellow",
+	"timed_out": false,
+	"number_of_nodes": 1,
+	"number_of_data_nodes": 1,
+	"active_primary_shards": 3,
+	"active_shards": 3,
+	"relocating_shards": 0,
+	"initializing_shards": 0,
+	"unassigned_shards": 1,
+	"unassigned_primary_shards": 0,
+	"delayed_unassigned_shards": 0,
+	"number_of_pending_tasks": 0,
+	"number_of_in_flight_fetch": 0,
+	"task_max_waiting_in_queue_millis": 0,
+	"active_shards_percent_as_number": 75.0
+}`
+
+	Nodes8Body = `
+{
+	"_nodes": {"total": 1, "successful": 1, "failed": 0},
+	"cluster_name": "my-cluster-8",
+	"nodes": {
+		"H8rS3c1fQ6mGQxS2hXvq6A": {
+			"timestamp": 1700000000000,
+			"name": "es8-node-1",
+			"transport_address": "172.18.0.2:9300",
+			"host": "172.18.0.2",
+			"ip": "172.18.0.2:9300",
+			"roles": ["data_content", "data_hot", "ingest", "master"],
+			"attributes": {"ml.allocated_processors": "8", "xpack.installed": "true"},
+			"indices": {
+				"docs": {"count": 1500, "deleted": 10},
+				"shard_stats": {"total_count": 3},
+				"store": {"size_in_bytes": 1048576, "total_data_set_size_in_bytes": 1048576, "reserved_in_bytes": 0},
+				"indexing": {
+					"index_total": 1500, "index_time_in_millis": 900, "index_current": 0, "index_failed": 0,
+					"delete_total": 0, "delete_time_in_millis": 0, "delete_current": 0,
+					"noop_update_total": 0, "is_throttled": false, "throttle_time_in_millis": 0,
+					"write_load": 0.00012
+				},
+				"search": {"query_total": 40, "query_time_in_millis": 120, "fetch_total": 38, "fetch_time_in_millis": 20},
+				"query_cache": {"memory_size_in_bytes": 2048, "total_count": 10, "hit_count": 4, "miss_count": 6, "cache_size": 2, "cache_count": 2, "evictions": 0},
+				"request_cache": {"memory_size_in_bytes": 512, "evictions": 0, "hit_count": 7, "miss_count": 3},
+				"segments": {"count": 9, "memory_in_bytes": 0, "terms_memory_in_bytes": 0, "index_writer_memory_in_bytes": 0, "file_sizes": {}},
+				"bulk": {"total_operations": 15, "total_time_in_millis": 950, "total_size_in_bytes": 700000, "avg_time_in_millis": 63, "avg_size_in_bytes": 46666},
+				"mappings": {"total_count": 40, "total_estimated_overhead_in_bytes": 40960}
+			}
+		}
+	}
+}`
+
+	Indices8Body = `
+{
+	"_shards": {"total": 2, "successful": 1, "failed": 0},
+	"_all": {
+		"primaries": {"docs": {"count": 1500, "deleted": 10}, "shard_stats": {"total_count": 1}},
+		"total": {"docs": {"count": 1500, "deleted": 10}, "shard_stats": {"total_count": 1}}
+	},
+	"indices": {
+		"logs-000001": {
+			"uuid": "lKqLiHv8SsmpzMVr5uTnOA",
+			"health": "yellow",
+			"status": "open",
+			"primaries": {
+				"docs": {"count": 1500, "deleted": 10},
+				"store": {"size_in_bytes": 1048576, "total_data_set_size_in_bytes": 1048576, "reserved_in_bytes": 0},
+				"indexing": {"index_total": 1500, "index_time_in_millis": 900, "write_load": 0.00012},
+				"request_cache": {"memory_size_in_bytes": 512, "evictions": 0, "hit_count": 7, "miss_count": 3},
+				"bulk": {"total_operations": 15, "total_time_in_millis": 950}
+			},
+			"total": {
+				"docs": {"count": 1500, "deleted": 10},
+				"store": {"size_in_bytes": 1048576, "total_data_set_size_in_bytes": 1048576, "reserved_in_bytes": 0}
+			}
+		}
+	}
+}`
+
+	Search8Body = `
+{
+	"took": 3,
+	"timed_out": false,
+	"_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+	"hits": {
+		"total": {"value": 1, "relation": "eq"},
+		"max_score": 1.0,
+		"hits": [
+			{"_index": "logs-000001", "_id": "1", "_score": 1.0, "_source": {"message": "hello"}}
+		]
+	}
+}`
+
+	Search8CompatBody = `
+{
+	"took": 3,
+	"timed_out": false,
+	"_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+	"hits": {
+		"total": {"value": 1, "relation": "eq"},
+		"max_score": 1.0,
+		"hits": [
+			{"_index": "logs-000001", "_type": "_doc", "_id": "1", "_score": 1.0, "_source": {"message": "hello"}}
+		]
+	}
+}`
+
+	Search8Hits = []model.SearchHit{
+		{Index: "logs-000001", ID: "1", Source: []byte(`{"message": "hello"}`)},
+	}
+)
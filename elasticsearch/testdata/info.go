@@ -0,0 +1,87 @@
+package testdata
+
+import "github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+
+// Test data for root endpoint info
+var (
+	InfoBody = `
+{
+	"name": "es-node-1",
+	"cluster_name": "my-cluster",
+	"cluster_uuid": "9FY2_MeXT4Wb2tEyKPaE4A",
+	"version": {
+		"number": "7.10.2",
+		"build_flavor": "default",
+		"build_type": "docker",
+		"build_hash": "747e1cc71def077253878a59143c1f785afa92b9",
+		"build_date": "2021-01-13T00:42:12.435326Z",
+		"build_snapshot": false,
+		"lucene_version": "8.7.0",
+		"minimum_wire_compatibility_version": "6.8.0",
+		"minimum_index_compatibility_version": "6.0.0-beta1"
+	},
+	"tagline": "You Know, for Search"
+}`
+
+	Info = &model.Info{
+		Name:        "es-node-1",
+		ClusterName: "my-cluster",
+		ClusterUUID: "9FY2_MeXT4Wb2tEyKPaE4A",
+		Version: model.InfoVersion{
+			Number:        "7.10.2",
+			BuildFlavor:   "default",
+			BuildType:     "docker",
+			BuildHash:     "747e1cc71def077253878a59143c1f785afa92b9",
+			LuceneVersion: "8.7.0",
+		},
+		Tagline: "You Know, for Search",
+	}
+
+	InfoOpenSearchBody = `
+{
+	"name": "os-node-1",
+	"cluster_name": "my-cluster",
+	"cluster_uuid": "Xk3u5hfLQqO6m8Ja3vVt3w",
+	"version": {
+		"distribution": "opensearch",
+		"number": "2.11.0",
+		"build_type": "tar",
+		"build_hash": "4dcad6dd1fd45b6bd91f041a041829c8687278fa",
+		"build_snapshot": false,
+		"lucene_version": "9.7.0",
+		"minimum_wire_compatibility_version": "7.10.0",
+		"minimum_index_compatibility_version": "7.0.0"
+	},
+	"tagline": "The OpenSearch Project: https://opensearch.org/"
+}`
+
+	Info1xBody = `
+{
+	"status": 200,
+	"name": "es-node-1",
+	"cluster_name": "my-cluster",
+	"version": {
+		"number": "1.7.5",
+		"build_hash": "00f95f4ffca6de89d68b7ccaf80d148f1f70e4d4",
+		"build_snapshot": false,
+		"lucene_version": "4.10.4"
+	},
+	"tagline": "You Know, for Search"
+}`
+
+	// Nodes1xBody is a nodes stats response of ES 1.x with shard request cache named "query_cache"
+	Nodes1xBody = `
+{
+	"cluster_name": "my-cluster",
+	"nodes": {
+		"3a6VFkY8SLOI4J6ljALdhQ": {
+			"name": "es-node-1",
+			"host": "es-node-1",
+			"indices": {
+				"filter_cache": {"memory_size_in_bytes": 1024, "evictions": 1},
+				"query_cache": {"memory_size_in_bytes": 2048, "evictions": 2, "hit_count": 3, "miss_count": 4}
+			}
+		}
+	}
+}`
+)
@@ -0,0 +1,127 @@
+package testdata
+
+import "github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+
+// Test data for index lifecycle state
+var (
+	ILMExplainBody = `
+{
+	"indices": {
+		"logs-000001": {
+			"index": "logs-000001",
+			"managed": true,
+			"policy": "logs",
+			"lifecycle_date_millis": 1584230400000,
+			"age": "1.5d",
+			"phase": "warm",
+			"phase_time_millis": 1584316800000,
+			"action": "shrink",
+			"action_time_millis": 1584316810000,
+			"step": "ERROR",
+			"step_time_millis": 1584316820000,
+			"failed_step": "shrink",
+			"failed_step_retry_count": 2,
+			"step_info": {"type": "illegal_argument_exception", "reason": "not enough nodes"}
+		},
+		"twitter": {
+			"index": "twitter",
+			"managed": false
+		}
+	}
+}`
+
+	ISMExplainBody = `
+{
+	"logs-000001": {
+		"index.plugins.index_state_management.policy_id": "logs",
+		"index.opendistro.index_state_management.policy_id": "logs",
+		"index": "logs-000001",
+		"index_uuid": "ZL0Dd1ITQrWZGTVSBLO6nQ",
+		"policy_id": "logs",
+		"policy_seq_no": 0,
+		"policy_primary_term": 1,
+		"rolled_over": false,
+		"index_creation_date": 1584230400000,
+		"state": {"name": "warm", "start_time": 1584316800000},
+		"action": {"name": "force_merge", "start_time": 1584316810000, "index": 0, "failed": true, "consumed_retries": 2, "last_retry_time": 0},
+		"step": {"name": "attempt_call_force_merge", "start_time": 1584316820000, "step_status": "failed"},
+		"retry_info": {"failed": false, "consumed_retries": 0},
+		"info": {"message": "Failed to start force merge"},
+		"enabled": true
+	},
+	"twitter": {
+		"index.plugins.index_state_management.policy_id": null,
+		"index.opendistro.index_state_management.policy_id": null,
+		"enabled": null
+	},
+	"total_managed_indices": 1
+}`
+
+	ILMLifecycle = model.Lifecycle{
+		"logs-000001": {
+			Policy:             "logs",
+			Phase:              "warm",
+			Action:             "shrink",
+			Step:               "ERROR",
+			Failed:             true,
+			Retries:            2,
+			PhaseTimeInMillis:  1584316800000,
+			ActionTimeInMillis: 1584316810000,
+			StepTimeInMillis:   1584316820000,
+		},
+	}
+
+	ISMLifecycle = model.Lifecycle{
+		"logs-000001": {
+			Policy:             "logs",
+			Phase:              "warm",
+			Action:             "force_merge",
+			Step:               "attempt_call_force_merge",
+			Failed:             true,
+			Retries:            2,
+			PhaseTimeInMillis:  1584316800000,
+			ActionTimeInMillis: 1584316810000,
+			StepTimeInMillis:   1584316820000,
+		},
+	}
+)
+
+// Test data for segment replication state
+var (
+	SegmentReplicationBody = `
+[
+	{
+		"shardId": "[logs-000001][0]",
+		"target_node": "os-node-2",
+		"target_host": "10.36.8.104",
+		"checkpoints_behind": "1",
+		"bytes_behind": "4096",
+		"current_lag": "120",
+		"last_completed_lag": "85",
+		"rejected_requests": "0"
+	},
+	{
+		"shardId": "[twitter][1]",
+		"target_node": "os-node-2",
+		"target_host": "10.36.8.104",
+		"checkpoints_behind": "0",
+		"bytes_behind": "0",
+		"current_lag": "0",
+		"last_completed_lag": "12",
+		"rejected_requests": "0"
+	}
+]`
+
+	SegmentReplication = model.SegmentReplication{
+		{
+			ShardID:           "[logs-000001][0]",
+			TargetNode:        "os-node-2",
+			TargetHost:        "10.36.8.104",
+			CheckpointsBehind: "1",
+			BytesBehind:       "4096",
+			CurrentLag:        "120",
+			LastCompletedLag:  "85",
+			RejectedRequests:  "0",
+		},
+	}
+)
@@ -1,5 +1,9 @@
 package testdata
 
+import (
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
 // Test data for nodes info
 var (
 	NodesBody = `
@@ -377,14 +381,16 @@ var (
 						"read_operations": 38345976,
 						"write_operations": 491446342,
 						"read_kilobytes": 836695368,
-						"write_kilobytes": 4587166320
+						"write_kilobytes": 4587166320,
+						"io_time_in_millis": 93480112
 					}],
 					"total": {
 						"operations": 529792318,
 						"read_operations": 38345976,
 						"write_operations": 491446342,
 						"read_kilobytes": 836695368,
-						"write_kilobytes": 4587166320
+						"write_kilobytes": 4587166320,
+						"io_time_in_millis": 93480112
 					}
 				}
 			},
@@ -457,4 +463,26 @@ var (
 	}
 }
 	`
+
+	NodesIOStats = model.NodeFSIOStats{
+		Devices: []model.NodeFSIOStatsDevice{
+			{
+				DeviceName:      "dm-2",
+				Operations:      529792318,
+				ReadOperations:  38345976,
+				WriteOperations: 491446342,
+				ReadKilobytes:   836695368,
+				WriteKilobytes:  4587166320,
+				IOTimeInMillis:  93480112,
+			},
+		},
+		Total: model.NodeFSIOStatsDevice{
+			Operations:      529792318,
+			ReadOperations:  38345976,
+			WriteOperations: 491446342,
+			ReadKilobytes:   836695368,
+			WriteKilobytes:  4587166320,
+			IOTimeInMillis:  93480112,
+		},
+	}
 )
@@ -0,0 +1,51 @@
+package testdata
+
+import (
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
+// Test data for search responses
+var (
+	SearchBody = `
+{
+	"took": 5,
+	"timed_out": false,
+	"_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+	"hits": {
+		"total": {"value": 42, "relation": "eq"},
+		"max_score": null,
+		"hits": []
+	},
+	"aggregations": {
+		"by_service": {
+			"doc_count_error_upper_bound": 0,
+			"sum_other_doc_count": 0,
+			"buckets": [
+				{"key": "api", "doc_count": 30},
+				{"key": "web", "doc_count": 12}
+			]
+		}
+	}
+}`
+
+	// SearchLegacyBody is a search response of ES prior to 7.0 with hits total as a number
+	SearchLegacyBody = `
+{
+	"took": 5,
+	"timed_out": false,
+	"hits": {"total": 42, "max_score": 0, "hits": []}
+}`
+
+	SearchHitsTotal = model.SearchHitsTotal{Value: 42, Relation: "eq"}
+
+	SearchAggregations = map[string]interface{}{
+		"by_service": map[string]interface{}{
+			"doc_count_error_upper_bound": 0.0,
+			"sum_other_doc_count":         0.0,
+			"buckets": []interface{}{
+				map[string]interface{}{"key": "api", "doc_count": 30.0},
+				map[string]interface{}{"key": "web", "doc_count": 12.0},
+			},
+		},
+	}
+)
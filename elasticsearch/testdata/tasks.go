@@ -0,0 +1,79 @@
+package testdata
+
+import "github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+
+// Test data for tasks info
+var (
+	TasksBody = `
+{
+	"nodes": {
+		"JhO_zXsHRhKARQm9I20mUw": {
+			"name": "es-node-1",
+			"transport_address": "10.36.8.103:9300",
+			"host": "10.36.8.103",
+			"ip": "10.36.8.103:9300",
+			"roles": ["data", "ingest", "master"],
+			"tasks": {
+				"JhO_zXsHRhKARQm9I20mUw:1024": {
+					"node": "JhO_zXsHRhKARQm9I20mUw",
+					"id": 1024,
+					"type": "transport",
+					"action": "indices:data/read/search",
+					"description": "indices[logs-*], search_type[QUERY_THEN_FETCH]",
+					"start_time_in_millis": 1584275400000,
+					"running_time_in_nanos": 1534222000,
+					"cancellable": true,
+					"cancelled": false,
+					"headers": {}
+				},
+				"JhO_zXsHRhKARQm9I20mUw:1025": {
+					"node": "JhO_zXsHRhKARQm9I20mUw",
+					"id": 1025,
+					"type": "transport",
+					"action": "indices:data/read/search[phase/query]",
+					"description": "shardId[[logs-2020.03.15][0]]",
+					"start_time_in_millis": 1584275400100,
+					"running_time_in_nanos": 1434100000,
+					"cancellable": true,
+					"cancelled": true,
+					"parent_task_id": "JhO_zXsHRhKARQm9I20mUw:1024",
+					"headers": {}
+				}
+			}
+		}
+	}
+}`
+
+	Tasks = model.Tasks{
+		Nodes: map[string]model.TasksNode{
+			"JhO_zXsHRhKARQm9I20mUw": {
+				Name: "es-node-1",
+				Host: "10.36.8.103",
+				Tasks: map[string]model.Task{
+					"JhO_zXsHRhKARQm9I20mUw:1024": {
+						Node:               "JhO_zXsHRhKARQm9I20mUw",
+						ID:                 1024,
+						Type:               "transport",
+						Action:             "indices:data/read/search",
+						Description:        "indices[logs-*], search_type[QUERY_THEN_FETCH]",
+						StartTimeInMillis:  1584275400000,
+						RunningTimeInNanos: 1534222000,
+						Cancellable:        true,
+					},
+					"JhO_zXsHRhKARQm9I20mUw:1025": {
+						Node:               "JhO_zXsHRhKARQm9I20mUw",
+						ID:                 1025,
+						Type:               "transport",
+						Action:             "indices:data/read/search[phase/query]",
+						Description:        "shardId[[logs-2020.03.15][0]]",
+						StartTimeInMillis:  1584275400100,
+						RunningTimeInNanos: 1434100000,
+						Cancellable:        true,
+						Cancelled:          true,
+						ParentTaskID:       "JhO_zXsHRhKARQm9I20mUw:1024",
+					},
+				},
+			},
+		},
+	}
+)
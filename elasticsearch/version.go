@@ -0,0 +1,149 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch/model"
+)
+
+// Cluster distributions
+const (
+	DistributionElasticsearch = "elasticsearch"
+	DistributionOpenSearch    = "opensearch"
+)
+
+// Version is a detected distribution and version of the cluster.
+// Zero value means that version is unknown yet
+type Version struct {
+	Distribution string
+	Number       string
+	Major        int
+	Minor        int
+	Lucene       string
+}
+
+// ParseVersion returns version of the cluster from root endpoint response
+func ParseVersion(info *model.Info) (Version, error) {
+	v := Version{
+		Distribution: DistributionElasticsearch,
+		Number:       info.Version.Number,
+		Lucene:       info.Version.LuceneVersion,
+	}
+	if info.Version.Distribution != "" {
+		v.Distribution = info.Version.Distribution
+	}
+
+	var err error
+	if v.Major, v.Minor, err = parseMajorMinor(v.Number); err != nil {
+		return Version{}, err
+	}
+
+	return v, nil
+}
+
+// Known reports whether version was detected
+func (v Version) Known() bool {
+	return v.Number != ""
+}
+
+// String returns human readable version, e.g. "elasticsearch 7.10.2"
+func (v Version) String() string {
+	if !v.Known() {
+		return "unknown"
+	}
+	return v.Distribution + " " + v.Number
+}
+
+// IsOpenSearch reports whether the cluster is OpenSearch
+func (v Version) IsOpenSearch() bool {
+	return v.Distribution == DistributionOpenSearch
+}
+
+// openSearchAtLeast reports whether the cluster is OpenSearch of the same or newer version than given one
+func (v Version) openSearchAtLeast(major, minor int) bool {
+	return v.IsOpenSearch() && (v.Major > major || v.Major == major && v.Minor >= minor)
+}
+
+// compatible returns major and minor version of Elasticsearch with the same API.
+// OpenSearch is a fork of Elasticsearch 7.10
+func (v Version) compatible() (int, int) {
+	if v.IsOpenSearch() {
+		return 7, 10
+	}
+	return v.Major, v.Minor
+}
+
+// atLeast reports whether version is the same or newer than given Elasticsearch version.
+// Unknown version is treated as the newest one
+func (v Version) atLeast(major, minor int) bool {
+	if !v.Known() {
+		return true
+	}
+	vMajor, vMinor := v.compatible()
+	return vMajor > major || vMajor == major && vMinor >= minor
+}
+
+// VersionRange is a range of Elasticsearch versions [since, until) where an API or stats field exists
+type VersionRange struct {
+	since *[2]int
+	until *[2]int
+}
+
+// Since returns range of versions starting with given "major.minor" version
+func Since(version string) VersionRange {
+	return VersionRange{since: mustParseMajorMinor(version)}
+}
+
+// Until returns range of versions older than given "major.minor" version
+func Until(version string) VersionRange {
+	return VersionRange{until: mustParseMajorMinor(version)}
+}
+
+// Between returns range of versions starting with since and older than until
+func Between(since, until string) VersionRange {
+	return VersionRange{since: mustParseMajorMinor(since), until: mustParseMajorMinor(until)}
+}
+
+// Contains reports whether version is in the range.
+// Zero range contains all versions, unknown version is contained by all ranges
+func (r VersionRange) Contains(v Version) bool {
+	if !v.Known() {
+		return true
+	}
+	if r.since != nil && !v.atLeast(r.since[0], r.since[1]) {
+		return false
+	}
+	if r.until != nil && v.atLeast(r.until[0], r.until[1]) {
+		return false
+	}
+	return true
+}
+
+func mustParseMajorMinor(version string) *[2]int {
+	major, minor, err := parseMajorMinor(version)
+	if err != nil {
+		panic(err)
+	}
+	return &[2]int{major, minor}
+}
+
+// parseMajorMinor parses major and minor parts of version like "7.10.2" or "8.0.0-SNAPSHOT"
+func parseMajorMinor(version string) (int, int, error) {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("invalid version %q", version)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid major version in %q", version)
+	}
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid minor version in %q", version)
+	}
+
+	return major, minor, nil
+}
@@ -0,0 +1,37 @@
+package elasticsearch
+
+import "testing"
+
+func TestVersionRange_Contains(t *testing.T) {
+	var (
+		es1        = Version{Distribution: DistributionElasticsearch, Number: "1.7.5", Major: 1, Minor: 7}
+		es63       = Version{Distribution: DistributionElasticsearch, Number: "6.3.0", Major: 6, Minor: 3}
+		es8        = Version{Distribution: DistributionElasticsearch, Number: "8.11.1", Major: 8, Minor: 11}
+		opensearch = Version{Distribution: DistributionOpenSearch, Number: "2.11.0", Major: 2, Minor: 11}
+	)
+
+	tests := []struct {
+		name    string
+		r       VersionRange
+		version Version
+		want    bool
+	}{
+		{"zero range", VersionRange{}, es1, true},
+		{"unknown version", Since("7.7"), Version{}, true},
+		{"since older", Since("2.0"), es1, false},
+		{"since same", Since("6.3"), es63, true},
+		{"until newer", Until("8.0"), es63, true},
+		{"until same major", Until("8.0"), es8, false},
+		{"between", Between("5.0", "8.0"), es63, true},
+		{"opensearch is 7.10", Between("7.7", "8.0"), opensearch, true},
+		{"opensearch is not 8.x", Since("8.0"), opensearch, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.Contains(tt.version); got != tt.want {
+				t.Fatalf("Contains(%s) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ require (
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.1 // indirect
 	github.com/prometheus/client_golang v1.1.0
-
+	github.com/prometheus/client_model v0.0.0-20190129233127-fd36f4220a90
 	gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405
 )
 
@@ -1,6 +1,9 @@
 package httpclient
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+)
 
 // Client sends http.Requests and returns http.Responses or errors in  case of failure.
 type Client interface {
@@ -26,3 +29,6 @@ func Decorate(c Client, ds ...DecoratorFunc) Client {
 	}
 	return result
 }
+
+// ErrCircuitOpen is returned for requests which weren't sent because circuit breaker of the endpoint is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
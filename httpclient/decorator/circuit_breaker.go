@@ -0,0 +1,177 @@
+package decorator
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Circuit breaker states, values of state label of the state metric
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+var circuitStates = []string{CircuitClosed, CircuitOpen, CircuitHalfOpen}
+
+// CircuitBreakerConfig is a configuration of circuit breakers
+type CircuitBreakerConfig struct {
+	// Failures is a number of consecutive failed or slow requests which opens the breaker
+	Failures int
+	// SlowThreshold is a duration until response headers after which request is counted as failed. Zero disables it
+	SlowThreshold time.Duration
+	// CoolDown is a time the breaker stays open before a probe request is let through
+	CoolDown time.Duration
+}
+
+// CircuitBreaker keeps a circuit breaker per normalized endpoint and exports their states.
+// Implements prometheus.Collector
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+
+	state *prometheus.GaugeVec
+}
+
+// circuit is a state of endpoint circuit breaker
+type circuit struct {
+	state    string
+	failures int
+	openedAt time.Time
+	// probing is set while the only request let through half-open breaker is in flight
+	probing bool
+}
+
+// NewCircuitBreaker returns new circuit breakers with given configuration
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		config:   config,
+		now:      time.Now,
+		circuits: make(map[string]*circuit),
+		state: metrics.NewGaugeVec(
+			"exporter", "circuit_breaker_state",
+			"State of ElasticSearch API endpoint circuit breaker, 1 for the current state",
+			[]string{"endpoint", "state"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (b *CircuitBreaker) Describe(ch chan<- *prometheus.Desc) {
+	b.state.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface
+func (b *CircuitBreaker) Collect(ch chan<- prometheus.Metric) {
+	b.state.Collect(ch)
+}
+
+// CircuitBreakerDecorator returns a DecoratorFunc that stops requesting an endpoint after consecutive failures,
+// so an overloaded cluster isn't loaded further by scrapes. Transport errors, 429 and 5xx responses and responses
+// slower than the threshold are failures. Open breaker fails requests with httpclient.ErrCircuitOpen
+// for a cool-down period, then lets a single probe request through and closes if it succeeds
+func CircuitBreakerDecorator(b *CircuitBreaker) httpclient.DecoratorFunc {
+	return func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (res *http.Response, err error) {
+			endpoint := normalizeEndpoint(r.URL.Path)
+			if !b.allow(endpoint) {
+				return nil, fmt.Errorf("%s: %w", endpoint, httpclient.ErrCircuitOpen)
+			}
+
+			// result is recorded even if the request panics, so half-open breaker isn't left probing forever
+			failed := true
+			defer func() { b.done(endpoint, failed) }()
+
+			start := b.now()
+			res, err = c.Do(r)
+			slow := b.config.SlowThreshold > 0 && b.now().Sub(start) > b.config.SlowThreshold
+			failed = err != nil || slow || isOverloaded(res.StatusCode)
+
+			return res, err
+		})
+	}
+}
+
+// allow reports whether request to endpoint can be sent
+func (b *CircuitBreaker) allow(endpoint string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(endpoint)
+	switch c.state {
+	case CircuitOpen:
+		if b.now().Sub(c.openedAt) < b.config.CoolDown {
+			return false
+		}
+		b.setState(endpoint, c, CircuitHalfOpen)
+		c.probing = true
+		return true
+	case CircuitHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// done records result of request to endpoint
+func (b *CircuitBreaker) done(endpoint string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(endpoint)
+	if c.state == CircuitHalfOpen {
+		c.probing = false
+	}
+
+	if !failed {
+		c.failures = 0
+		b.setState(endpoint, c, CircuitClosed)
+		return
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= b.config.Failures {
+		c.openedAt = b.now()
+		b.setState(endpoint, c, CircuitOpen)
+	}
+}
+
+// circuit returns circuit of endpoint, new circuits are closed
+func (b *CircuitBreaker) circuit(endpoint string) *circuit {
+	c, ok := b.circuits[endpoint]
+	if !ok {
+		c = &circuit{}
+		b.circuits[endpoint] = c
+		b.setState(endpoint, c, CircuitClosed)
+	}
+	return c
+}
+
+func (b *CircuitBreaker) setState(endpoint string, c *circuit, state string) {
+	c.state = state
+	for _, s := range circuitStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		b.state.WithLabelValues(endpoint, s).Set(value)
+	}
+}
+
+// isOverloaded reports whether response status means that ES can't handle the request now
+func isOverloaded(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
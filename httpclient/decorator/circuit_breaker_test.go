@@ -0,0 +1,140 @@
+package decorator
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestCircuitBreakerDecorator(c *C) {
+	now := time.Date(2020, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	var (
+		status  int
+		latency time.Duration
+		calls   int
+	)
+	server := httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		now = now.Add(latency)
+		if status == 0 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: status, Request: r}, nil
+	})
+
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{Failures: 2, SlowThreshold: time.Second, CoolDown: time.Minute})
+	breaker.now = func() time.Time { return now }
+	httpClient := httpclient.Decorate(server, CircuitBreakerDecorator(breaker))
+
+	do := func(path string, code int, d time.Duration) error {
+		status, latency = code, d
+		r, _ := http.NewRequest("GET", path, nil)
+		_, err := httpClient.Do(r)
+		return err
+	}
+	state := func(endpoint string) string {
+		for _, s := range circuitStates {
+			if testutil.ToFloat64(breaker.state.WithLabelValues(endpoint, s)) == 1 {
+				return s
+			}
+		}
+		return ""
+	}
+
+	// success resets consecutive failures, client errors aren't failures
+	c.Assert(do("/_nodes/_local/stats", 500, 0), IsNil)
+	c.Assert(do("/_nodes/_local/stats", 200, 0), IsNil)
+	c.Assert(do("/_nodes/_local/stats", 404, 0), IsNil)
+	c.Assert(do("/_nodes/_local/stats", 503, 0), IsNil)
+	c.Assert(state("/_nodes/stats"), Equals, CircuitClosed)
+
+	// slow response is a failure
+	c.Assert(do("/_nodes/_local/stats", 200, 2*time.Second), IsNil)
+	c.Assert(state("/_nodes/stats"), Equals, CircuitOpen)
+
+	calls = 0
+	err := do("/_nodes/node-1/stats", 200, 0)
+	c.Assert(errors.Is(err, httpclient.ErrCircuitOpen), Equals, true)
+	c.Assert(err, ErrorMatches, "/_nodes/stats: circuit breaker is open")
+	c.Assert(calls, Equals, 0)
+
+	// other endpoints are requested
+	c.Assert(do("/_stats", 200, 0), IsNil)
+	c.Assert(state("/_stats"), Equals, CircuitClosed)
+
+	// failed probe opens the breaker again
+	now = now.Add(time.Minute)
+	c.Assert(do("/_nodes/_local/stats", 0, 0), ErrorMatches, "connection refused")
+	c.Assert(state("/_nodes/stats"), Equals, CircuitOpen)
+	c.Assert(do("/_nodes/_local/stats", 200, 0), ErrorMatches, ".*circuit breaker is open")
+
+	// successful probe closes the breaker
+	now = now.Add(time.Minute)
+	c.Assert(do("/_nodes/_local/stats", 200, 0), IsNil)
+	c.Assert(state("/_nodes/stats"), Equals, CircuitClosed)
+	c.Assert(do("/_nodes/_local/stats", 200, 0), IsNil)
+}
+
+func (s *TestSuite) TestCircuitBreaker_HalfOpen(c *C) {
+	now := time.Date(2020, 3, 15, 10, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{Failures: 1, CoolDown: time.Minute})
+	breaker.now = func() time.Time { return now }
+
+	breaker.done("/_stats", true)
+	c.Assert(breaker.allow("/_stats"), Equals, false)
+
+	// only one probe is let through half-open breaker
+	now = now.Add(time.Minute)
+	c.Assert(breaker.allow("/_stats"), Equals, true)
+	c.Assert(breaker.allow("/_stats"), Equals, false)
+	c.Assert(testutil.ToFloat64(breaker.state.WithLabelValues("/_stats", CircuitHalfOpen)), Equals, 1.0)
+
+	breaker.done("/_stats", false)
+	c.Assert(breaker.allow("/_stats"), Equals, true)
+	c.Assert(breaker.allow("/_stats"), Equals, true)
+}
+
+func (s *TestSuite) TestCircuitBreakerDecorator_Panic(c *C) {
+	now := time.Date(2020, 3, 15, 10, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{Failures: 1, CoolDown: time.Minute})
+	breaker.now = func() time.Time { return now }
+
+	panics := true
+	httpClient := httpclient.Decorate(httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		if panics {
+			panic("decoder failure")
+		}
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	}), CircuitBreakerDecorator(breaker))
+
+	do := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		r, _ := http.NewRequest("GET", "/_stats", nil)
+		_, err = httpClient.Do(r)
+		return err
+	}
+
+	// panic is a failure, which opens the breaker
+	c.Assert(do(), ErrorMatches, "panic: decoder failure")
+	c.Assert(testutil.ToFloat64(breaker.state.WithLabelValues("/_stats", CircuitOpen)), Equals, 1.0)
+
+	// panicking probe opens the breaker again instead of leaving it probing
+	now = now.Add(time.Minute)
+	c.Assert(do(), ErrorMatches, "panic: decoder failure")
+	c.Assert(testutil.ToFloat64(breaker.state.WithLabelValues("/_stats", CircuitOpen)), Equals, 1.0)
+
+	now = now.Add(time.Minute)
+	panics = false
+	c.Assert(do(), IsNil)
+	c.Assert(testutil.ToFloat64(breaker.state.WithLabelValues("/_stats", CircuitClosed)), Equals, 1.0)
+}
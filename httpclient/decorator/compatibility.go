@@ -0,0 +1,27 @@
+package decorator
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+)
+
+// CompatibilityDecorator returns a DecoratorFunc that asks Elasticsearch to respond in format of
+// given major version with REST API compatibility headers. Content-Type is set for requests with body only.
+// Compatibility headers are supported by Elasticsearch 7.11+ and rejected by older versions and OpenSearch
+func CompatibilityDecorator(compatibleWith int) httpclient.DecoratorFunc {
+	mediaType := "application/vnd.elasticsearch+json; compatible-with=" + strconv.Itoa(compatibleWith)
+
+	return func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (res *http.Response, err error) {
+			reqCopy := r.Clone(r.Context())
+			reqCopy.Header.Set("Accept", mediaType)
+			if r.Body != nil && r.Body != http.NoBody {
+				reqCopy.Header.Set("Content-Type", mediaType)
+			}
+
+			return c.Do(reqCopy)
+		})
+	}
+}
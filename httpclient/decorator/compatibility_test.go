@@ -0,0 +1,28 @@
+package decorator
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestCompatibilityDecorator(c *C) {
+	httpClient := httpclient.Decorate(s.dummyClient, CompatibilityDecorator(7))
+
+	r, _ := http.NewRequest("GET", "/_nodes/stats", nil)
+	res, _ := httpClient.Do(r)
+
+	c.Assert(res.Request.Header.Get("Accept"), Equals, "application/vnd.elasticsearch+json; compatible-with=7")
+	c.Assert(res.Request.Header.Get("Content-Type"), Equals, "")
+	c.Assert(r.Header.Get("Accept"), Equals, "", Commentf("original request must not be modified"))
+
+	r, _ = http.NewRequest("POST", "/logs-*/_search", strings.NewReader(`{"size": 0}`))
+	r.Header.Set("Content-Type", "application/json")
+	res, _ = httpClient.Do(r)
+
+	c.Assert(res.Request.Header.Get("Accept"), Equals, "application/vnd.elasticsearch+json; compatible-with=7")
+	c.Assert(res.Request.Header.Get("Content-Type"), Equals, "application/vnd.elasticsearch+json; compatible-with=7")
+	c.Assert(r.Header.Get("Content-Type"), Equals, "application/json")
+}
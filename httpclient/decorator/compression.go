@@ -0,0 +1,111 @@
+package decorator
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CompressionDecorator returns a DecoratorFunc that asks for gzip or deflate compressed responses
+// and decompresses them. http.Transport doesn't decompress responses when Accept-Encoding is set
+// by the caller, so responses are decompressed regardless of transport settings.
+// Response body bytes are counted as received over the network and after decompression
+func CompressionDecorator(compressedBytes, uncompressedBytes prometheus.Counter) httpclient.DecoratorFunc {
+	return func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (res *http.Response, err error) {
+			reqCopy := r.Clone(r.Context())
+			reqCopy.Header.Set("Accept-Encoding", "gzip, deflate")
+
+			res, err = c.Do(reqCopy)
+			if err != nil || res.Body == nil {
+				return res, err
+			}
+
+			raw := &countingReader{Reader: res.Body, counter: compressedBytes}
+			body := &decompressingBody{raw: raw, closer: res.Body, counter: uncompressedBytes}
+
+			encoding := strings.ToLower(res.Header.Get("Content-Encoding"))
+			switch encoding {
+			case "gzip":
+				body.decompress = func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
+			case "deflate":
+				body.decompress = func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }
+			case "":
+				body.decompress = func(r io.Reader) (io.Reader, error) { return r, nil }
+			default:
+				// unknown encoding is passed as is, only received bytes are known
+				res.Body = &countingBody{countingReader: raw, closer: res.Body}
+				return res, nil
+			}
+
+			if encoding != "" {
+				res.Header.Del("Content-Encoding")
+				res.Header.Del("Content-Length")
+				res.ContentLength = -1
+				res.Uncompressed = true
+			}
+			res.Body = body
+
+			return res, nil
+		})
+	}
+}
+
+// countingReader adds number of read bytes to counter
+type countingReader struct {
+	io.Reader
+	counter prometheus.Counter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.counter.Add(float64(n))
+	return n, err
+}
+
+// countingBody is a response body with counted bytes
+type countingBody struct {
+	*countingReader
+	closer io.Closer
+}
+
+func (b *countingBody) Close() error {
+	return b.closer.Close()
+}
+
+// decompressingBody decompresses response body on the first read, so decompression errors
+// are returned from Read like any other body read errors
+type decompressingBody struct {
+	raw        io.Reader
+	closer     io.Closer
+	decompress func(io.Reader) (io.Reader, error)
+	counter    prometheus.Counter
+
+	reader io.Reader
+	err    error
+}
+
+func (b *decompressingBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = b.decompress(b.raw)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.reader.Read(p)
+	b.counter.Add(float64(n))
+	return n, err
+}
+
+func (b *decompressingBody) Close() error {
+	if closer, ok := b.reader.(io.Closer); ok && b.err == nil {
+		closer.Close()
+	}
+	return b.closer.Close()
+}
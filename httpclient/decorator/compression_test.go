@@ -0,0 +1,84 @@
+package decorator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestCompressionDecorator(c *C) {
+	const body = `{"nodes": {"node-1": {"name": "node-1"}, "node-2": {"name": "node-2"}}}`
+
+	compress := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"":        nil,
+	}
+
+	for encoding, newWriter := range compress {
+		encoded := []byte(body)
+		if newWriter != nil {
+			var buf bytes.Buffer
+			w := newWriter(&buf)
+			w.Write(encoded)
+			w.Close()
+			encoded = buf.Bytes()
+		}
+
+		server := httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+			c.Assert(r.Header.Get("Accept-Encoding"), Equals, "gzip, deflate")
+
+			res := &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader(encoded))}
+			if encoding != "" {
+				res.Header.Set("Content-Encoding", encoding)
+			}
+			return res, nil
+		})
+
+		compressed := prometheus.NewCounter(prometheus.CounterOpts{Name: "compressed"})
+		uncompressed := prometheus.NewCounter(prometheus.CounterOpts{Name: "uncompressed"})
+		httpClient := httpclient.Decorate(server, CompressionDecorator(compressed, uncompressed))
+
+		r, _ := http.NewRequest("GET", "/_nodes/stats", nil)
+		res, err := httpClient.Do(r)
+		c.Assert(err, IsNil)
+
+		got, err := ioutil.ReadAll(res.Body)
+		c.Assert(err, IsNil, Commentf("encoding %q", encoding))
+		c.Assert(res.Body.Close(), IsNil)
+
+		c.Assert(string(got), Equals, body, Commentf("encoding %q", encoding))
+		c.Assert(res.Header.Get("Content-Encoding"), Equals, "")
+		c.Assert(testutil.ToFloat64(compressed), Equals, float64(len(encoded)))
+		c.Assert(testutil.ToFloat64(uncompressed), Equals, float64(len(body)))
+		c.Assert(r.Header.Get("Accept-Encoding"), Equals, "", Commentf("original request must not be modified"))
+	}
+}
+
+func (s *TestSuite) TestCompressionDecorator_Malformed(c *C) {
+	server := httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		res := &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader([]byte(`{}`)))}
+		res.Header.Set("Content-Encoding", "gzip")
+		return res, nil
+	})
+
+	compressed := prometheus.NewCounter(prometheus.CounterOpts{Name: "compressed"})
+	uncompressed := prometheus.NewCounter(prometheus.CounterOpts{Name: "uncompressed"})
+	httpClient := httpclient.Decorate(server, CompressionDecorator(compressed, uncompressed))
+
+	r, _ := http.NewRequest("GET", "/_nodes/stats", nil)
+	res, err := httpClient.Do(r)
+	c.Assert(err, IsNil)
+
+	_, err = ioutil.ReadAll(res.Body)
+	c.Assert(err, NotNil)
+	c.Assert(res.Body.Close(), IsNil)
+}
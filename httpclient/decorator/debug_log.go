@@ -0,0 +1,183 @@
+package decorator
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+)
+
+// DefaultDebugLogBodyLimit is a default number of request and response body bytes logged by DebugLogDecorator
+const DefaultDebugLogBodyLimit = 1024
+
+// redacted replaces secret values in logged URLs and bodies
+const redacted = "[REDACTED]"
+
+// secretWords are parts of names of JSON fields and query parameters with secret values
+const secretWords = `password|passwd|secret|token|api_?key|authorization|credential`
+
+var (
+	secretKey = regexp.MustCompile(`(?i)` + secretWords)
+	// secretField matches JSON string fields with secret-like names. A value cut by truncation is matched till the end
+	secretField = regexp.MustCompile(`(?i)("[^"]*(?:` + secretWords + `)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+	// lineBreaks of pretty printed bodies are collapsed, so each request is logged as a single line
+	lineBreaks = regexp.MustCompile(`\s*[\r\n]+\s*`)
+)
+
+// DebugLog switches debug logging of requests at runtime
+type DebugLog struct {
+	enabled   int32
+	bodyLimit int
+}
+
+// NewDebugLog returns debug log switch. Up to bodyLimit bytes of request and response bodies are logged
+func NewDebugLog(enabled bool, bodyLimit int) *DebugLog {
+	l := &DebugLog{bodyLimit: bodyLimit}
+	l.SetEnabled(enabled)
+	return l
+}
+
+// Enabled reports whether requests are logged
+func (l *DebugLog) Enabled() bool {
+	return atomic.LoadInt32(&l.enabled) == 1
+}
+
+// SetEnabled enables or disables logging of requests
+func (l *DebugLog) SetEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&l.enabled, v)
+}
+
+// DebugLogDecorator returns a DecoratorFunc that logs method, URL, status, latency and bodies of requests
+// while the debug log is enabled. Bodies are truncated and values of secret-like fields are redacted.
+// Response is logged when its body is closed, so streamed responses aren't buffered
+func DebugLogDecorator(l *DebugLog) httpclient.DecoratorFunc {
+	return func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (res *http.Response, err error) {
+			if !l.Enabled() {
+				return c.Do(r)
+			}
+
+			var requestBody []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				requestBody, err = ioutil.ReadAll(r.Body)
+				r.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				r = r.Clone(r.Context())
+				r.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+			}
+
+			start := time.Now()
+			res, err = c.Do(r)
+			latency := time.Since(start)
+
+			request := r.Method + " " + redactURL(r.URL)
+			if err != nil {
+				log.Printf("DEBUG: %s failed in %s: %s, request body: %s", request, latency, err, l.format(requestBody, len(requestBody)))
+				return res, err
+			}
+			if res.Body == nil {
+				log.Printf("DEBUG: %s %d in %s, request body: %s", request, res.StatusCode, latency, l.format(requestBody, len(requestBody)))
+				return res, nil
+			}
+
+			res.Body = &loggedBody{
+				ReadCloser: res.Body,
+				limit:      l.bodyLimit,
+				log: func(body []byte, size int) {
+					log.Printf(
+						"DEBUG: %s %d in %s, request body: %s, response body: %s",
+						request, res.StatusCode, latency, l.format(requestBody, len(requestBody)), l.format(body, size),
+					)
+				},
+			}
+
+			return res, nil
+		})
+	}
+}
+
+// format returns redacted and truncated body, size is a full size of the body
+func (l *DebugLog) format(body []byte, size int) string {
+	if size == 0 {
+		return "<empty>"
+	}
+
+	truncated := size > l.bodyLimit
+	if truncated {
+		body = body[:l.bodyLimit]
+	}
+
+	result := secretField.ReplaceAllString(string(body), `$1"`+redacted+`"`)
+	result = strings.TrimSpace(lineBreaks.ReplaceAllString(result, " "))
+	if truncated {
+		result += "... (truncated, " + strconv.Itoa(size) + " bytes read)"
+	}
+
+	return result
+}
+
+// loggedBody keeps the beginning of response body and logs it on close
+type loggedBody struct {
+	io.ReadCloser
+	limit int
+	log   func(body []byte, size int)
+
+	head   []byte
+	size   int
+	closed bool
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if free := b.limit - len(b.head); free > 0 {
+		if free > n {
+			free = n
+		}
+		b.head = append(b.head, p[:free]...)
+	}
+	b.size += n
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.log(b.head, b.size)
+	}
+	return b.ReadCloser.Close()
+}
+
+// redactURL returns URL with password of user info and secret-like query parameters redacted
+func redactURL(u *url.URL) string {
+	redactedURL := *u
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			redactedURL.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+
+	query := u.Query()
+	for key := range query {
+		if secretKey.MatchString(key) {
+			query.Set(key, redacted)
+			redactedURL.RawQuery = query.Encode()
+		}
+	}
+
+	return redactedURL.String()
+}
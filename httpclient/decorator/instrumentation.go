@@ -0,0 +1,139 @@
+package decorator
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// codeError is a code label value of requests failed without response
+const codeError = "error"
+
+// RequestMetrics are metrics of ES API requests recorded by InstrumentationDecorator.
+// Implements prometheus.Collector
+type RequestMetrics struct {
+	duration     *prometheus.HistogramVec
+	requests     *prometheus.CounterVec
+	responseSize *prometheus.CounterVec
+}
+
+// NewRequestMetrics returns new metrics of ES API requests
+func NewRequestMetrics() *RequestMetrics {
+	return &RequestMetrics{
+		duration: metrics.NewHistogramVec(
+			"exporter", "request_duration_seconds",
+			"Duration of ElasticSearch API requests until response headers are received",
+			prometheus.DefBuckets, []string{"endpoint"},
+		),
+		requests: metrics.NewCounterVec(
+			"exporter", "requests_total",
+			"Total number of ElasticSearch API requests by response status code",
+			[]string{"endpoint", "code"},
+		),
+		responseSize: metrics.NewCounterVec(
+			"exporter", "response_size_bytes_total",
+			"Total size of ElasticSearch API response bodies as received",
+			[]string{"endpoint"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (m *RequestMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+	m.requests.Describe(ch)
+	m.responseSize.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface
+func (m *RequestMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+	m.requests.Collect(ch)
+	m.responseSize.Collect(ch)
+}
+
+// InstrumentationDecorator returns a DecoratorFunc that records duration, status code and response size
+// of requests labelled by normalized endpoint. Duration doesn't include reading of response body,
+// so time spent by exporter on decoding isn't accounted as ES latency
+func InstrumentationDecorator(m *RequestMetrics) httpclient.DecoratorFunc {
+	return func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (res *http.Response, err error) {
+			endpoint := normalizeEndpoint(r.URL.Path)
+
+			start := time.Now()
+			res, err = c.Do(r)
+			m.duration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+			if err != nil {
+				m.requests.WithLabelValues(endpoint, codeError).Inc()
+				return res, err
+			}
+			m.requests.WithLabelValues(endpoint, strconv.Itoa(res.StatusCode)).Inc()
+
+			if res.Body != nil {
+				res.Body = &countingBody{
+					countingReader: &countingReader{Reader: res.Body, counter: m.responseSize.WithLabelValues(endpoint)},
+					closer:         res.Body,
+				}
+			}
+
+			return res, nil
+		})
+	}
+}
+
+// apiWords are path segments of ES APIs which don't start with underscore, e.g. "/_nodes/stats"
+var apiWords = map[string]bool{
+	"stats":       true,
+	"health":      true,
+	"state":       true,
+	"explain":     true,
+	"settings":    true,
+	"mapping":     true,
+	"hot_threads": true,
+	"usage":       true,
+}
+
+// documentAPIs are APIs followed by document id
+var documentAPIs = map[string]bool{
+	"_doc":    true,
+	"_create": true,
+	"_update": true,
+	"_source": true,
+}
+
+// normalizeEndpoint returns request path with variable parts normalized, so it can be used as a label value:
+// index expressions are replaced by "{index}", document ids by "{id}", node ids and stats metrics are dropped.
+// E.g. "/_nodes/_local/stats/jvm" is "/_nodes/stats" and "/logs-*/_stats/docs" is "/{index}/_stats"
+func normalizeEndpoint(path string) string {
+	var result []string
+	for i, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		last := ""
+		if len(result) > 0 {
+			last = result[len(result)-1]
+		}
+
+		switch {
+		case segment == "":
+			continue
+		case i == 0 && !strings.HasPrefix(segment, "_"):
+			result = append(result, "{index}")
+		case last == "_nodes" && !apiWords[segment]:
+			// node ids and selectors like "_local"
+			continue
+		case last == "_cat":
+			result = append(result, segment)
+		case documentAPIs[last]:
+			result = append(result, "{id}")
+		case strings.HasPrefix(segment, "_") || apiWords[segment]:
+			result = append(result, segment)
+		}
+	}
+
+	return "/" + strings.Join(result, "/")
+}
@@ -0,0 +1,80 @@
+package decorator
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestInstrumentationDecorator(c *C) {
+	const body = `{"nodes": {}}`
+
+	server := httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/_tasks" {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(body)))}, nil
+	})
+
+	m := NewRequestMetrics()
+	httpClient := httpclient.Decorate(server, InstrumentationDecorator(m))
+
+	for _, path := range []string{"/_nodes/stats", "/_nodes/_local/stats/jvm?filter_path=nodes.*.jvm"} {
+		r, _ := http.NewRequest("GET", path, nil)
+		res, err := httpClient.Do(r)
+		c.Assert(err, IsNil)
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	r, _ := http.NewRequest("GET", "/_tasks", nil)
+	_, err := httpClient.Do(r)
+	c.Assert(err, ErrorMatches, "connection refused")
+
+	c.Assert(testutil.ToFloat64(m.requests.WithLabelValues("/_nodes/stats", "200")), Equals, float64(2))
+	c.Assert(testutil.ToFloat64(m.requests.WithLabelValues("/_tasks", "error")), Equals, float64(1))
+	c.Assert(testutil.ToFloat64(m.responseSize.WithLabelValues("/_nodes/stats")), Equals, float64(2*len(body)))
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(m)
+	families, err := registry.Gather()
+	c.Assert(err, IsNil)
+	for _, family := range families {
+		if family.GetName() == "elasticsearch_exporter_request_duration_seconds" {
+			c.Assert(family.GetMetric(), HasLen, 2)
+			c.Assert(family.GetMetric()[0].GetHistogram().GetSampleCount(), Equals, uint64(2))
+		}
+	}
+}
+
+func (s *TestSuite) TestNormalizeEndpoint(c *C) {
+	tests := map[string]string{
+		"/":                              "/",
+		"":                               "/",
+		"/_nodes/stats":                  "/_nodes/stats",
+		"/_nodes/_local/stats":           "/_nodes/stats",
+		"/_nodes/stats/indices,jvm":      "/_nodes/stats",
+		"/_stats":                        "/_stats",
+		"/_stats/docs,merge":             "/_stats",
+		"/logs-*,-logs-old/_stats/docs":  "/{index}/_stats",
+		"/*/_ilm/explain":                "/{index}/_ilm/explain",
+		"/_plugins/_ism/explain/*":       "/_plugins/_ism/explain",
+		"/_cat/tasks":                    "/_cat/tasks",
+		"/_cat/indices/logs-*":           "/_cat/indices",
+		"/_cluster/health/logs-000001":   "/_cluster/health",
+		"/canary/_doc/probe-1":           "/{index}/_doc/{id}",
+		"/logs-2020.03.15":               "/{index}",
+		"/logs-*/_search":                "/{index}/_search",
+		"/_snapshot/backups/snapshot-01": "/_snapshot",
+	}
+
+	for path, want := range tests {
+		c.Assert(normalizeEndpoint(path), Equals, want, Commentf("path %q", path))
+	}
+}
@@ -0,0 +1,76 @@
+package decorator
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+)
+
+// RecordDecorator returns a DecoratorFunc that writes every request and response to given directory,
+// so they can be served by httpclient.ReplayClient. Response is written when its body is closed,
+// the rest of the body which wasn't read by the caller is read before that. Write errors are logged
+// and don't fail requests
+func RecordDecorator(dir string) httpclient.DecoratorFunc {
+	return func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (res *http.Response, err error) {
+			var requestBody []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				requestBody, err = ioutil.ReadAll(r.Body)
+				r.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				r = r.Clone(r.Context())
+				r.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+			}
+
+			res, err = c.Do(r)
+			if err != nil || res.Body == nil {
+				return res, err
+			}
+
+			res.Body = &recordedBody{
+				ReadCloser: res.Body,
+				record: func(responseBody []byte) {
+					e := httpclient.NewExchange(r, requestBody, res, responseBody)
+					if err := e.WriteFile(dir); err != nil {
+						log.Println("ERROR: failed to record response: ", err)
+					}
+				},
+			}
+
+			return res, nil
+		})
+	}
+}
+
+// recordedBody keeps response body and records it on close
+type recordedBody struct {
+	io.ReadCloser
+	record func(body []byte)
+
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (b *recordedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *recordedBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		if _, err := b.buf.ReadFrom(b.ReadCloser); err != nil {
+			log.Println("ERROR: failed to read response to record: ", err)
+		} else {
+			b.record(b.buf.Bytes())
+		}
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,130 @@
+package decorator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestRecordDecorator(c *C) {
+	const (
+		stats  = `{"nodes": {"node-1": {"name": "node-1", "jvm": {"mem": {"heap_used_in_bytes": 1024}}}}}`
+		search = `{"hits": {"total": 7}}`
+		cat    = "green open logs-2020.03.15\n"
+		query  = `{"query": {"match_all": {}}}`
+	)
+
+	server := httpclient.NewClientMock()
+	server.Get("/_nodes/_local/stats?filter_path=nodes.*.name").WillReturn(200, stats)
+	server.Post("/logs-*/_search").WithBody(query).WillReturn(200, search)
+	server.Get("/_cat/indices").WillReturn(200, cat)
+	server.Get("/missing/_stats").WillReturn(404, `{"error": "index_not_found_exception"}`)
+
+	dir := c.MkDir()
+	recorder := httpclient.Decorate(server, RecordDecorator(dir))
+
+	requests := []struct {
+		method, url, body, response string
+		status                      int
+	}{
+		{"GET", "http://es-1:9200/_nodes/_local/stats?filter_path=nodes.*.name", "", stats, 200},
+		{"POST", "http://es-1:9200/logs-*/_search", query, search, 200},
+		{"GET", "http://es-1:9200/_cat/indices", "", cat, 200},
+		{"GET", "http://es-1:9200/missing/_stats", "", `{"error": "index_not_found_exception"}`, 404},
+	}
+
+	for _, req := range requests {
+		r, _ := http.NewRequest(req.method, req.url, strings.NewReader(req.body))
+		res, err := recorder.Do(r)
+		c.Assert(err, IsNil)
+
+		// response is recorded whole even if the caller doesn't read it to the end
+		head := make([]byte, 5)
+		_, err = res.Body.Read(head)
+		c.Assert(err, IsNil)
+		c.Assert(res.Body.Close(), IsNil)
+		c.Assert(string(head), Equals, req.response[:5])
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	c.Assert(files, HasLen, len(requests))
+
+	replay := httpclient.NewReplayClient(dir)
+	for _, req := range requests {
+		// host is ignored on replay
+		r, _ := http.NewRequest(req.method, strings.Replace(req.url, "es-1", "localhost", 1), strings.NewReader(req.body))
+		res, err := replay.Do(r)
+		c.Assert(err, IsNil, Commentf("%s %s", req.method, req.url))
+		c.Assert(res.StatusCode, Equals, req.status)
+
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if strings.HasPrefix(req.response, "{") {
+			var want, got interface{}
+			json.Unmarshal([]byte(req.response), &want)
+			c.Assert(json.Unmarshal(body, &got), IsNil)
+			c.Assert(got, DeepEquals, want)
+		} else {
+			c.Assert(string(body), Equals, req.response)
+		}
+	}
+
+	// requests are matched by body too
+	r, _ := http.NewRequest("POST", "/logs-*/_search", bytes.NewReader([]byte(`{"size": 0}`)))
+	_, err := replay.Do(r)
+	c.Assert(err, ErrorMatches, `no recorded response for POST /logs-\*/_search in .*`)
+}
+
+func (s *TestSuite) TestRecordDecorator_Scrapes(c *C) {
+	server := httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"hits": {"total": 7}}`
+		if r.Method == "PUT" {
+			body = `{"result": "created"}`
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Request: r}, nil
+	})
+
+	dir := c.MkDir()
+	recorder := httpclient.Decorate(server, RecordDecorator(dir))
+
+	// requests of a scrape: a query with rendered time range and a canary document write
+	scrape := func(client httpclient.Client, now time.Time, sequence int) {
+		requests := []struct{ method, url, body string }{
+			{"POST", "/logs-*/_search", fmt.Sprintf(
+				`{"query": {"range": {"@timestamp": {"gte": "%s", "lt": %d}}}}`,
+				now.Add(-5*time.Minute).Format("2006-01-02T15:04:05.000Z07:00"), now.UnixNano()/int64(time.Millisecond),
+			)},
+			{"PUT", "/canary/_doc/exporter", fmt.Sprintf(
+				`{"@timestamp": "%s", "exporter": "exporter", "sequence": %d}`, now.Format(time.RFC3339Nano), sequence,
+			)},
+		}
+		for _, req := range requests {
+			r, _ := http.NewRequest(req.method, req.url, strings.NewReader(req.body))
+			res, err := client.Do(r)
+			c.Assert(err, IsNil, Commentf("%s %s", req.method, req.url))
+			ioutil.ReadAll(res.Body)
+			c.Assert(res.Body.Close(), IsNil)
+		}
+	}
+
+	now := time.Date(2020, 3, 15, 10, 0, 0, 0, time.UTC)
+	scrape(recorder, now, 1)
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	c.Assert(files, HasLen, 2)
+
+	// requests repeated on the next scrape are recorded to the same files
+	scrape(recorder, now.Add(30*time.Second+123*time.Millisecond), 2)
+	again, _ := filepath.Glob(filepath.Join(dir, "*"))
+	c.Assert(again, DeepEquals, files)
+
+	// and replayed later
+	scrape(httpclient.NewReplayClient(dir), now.Add(time.Hour), 3)
+}
@@ -0,0 +1,39 @@
+package decorator
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/tracing"
+)
+
+// TracingDecorator returns a DecoratorFunc that records a span per request as a child of span
+// of request context, e.g. span of the scrape. The span ends when response headers are received
+func TracingDecorator(tracer *tracing.Tracer) httpclient.DecoratorFunc {
+	return func(c httpclient.Client) httpclient.Client {
+		return httpclient.ClientFunc(func(r *http.Request) (res *http.Response, err error) {
+			endpoint := normalizeEndpoint(r.URL.Path)
+
+			ctx, span := tracer.Start(r.Context(), "elasticsearch "+r.Method+" "+endpoint)
+			defer span.End()
+
+			span.SetAttribute("http.method", r.Method)
+			span.SetAttribute("http.url", redactURL(r.URL))
+			span.SetAttribute("endpoint", endpoint)
+
+			res, err = c.Do(r.WithContext(ctx))
+			if err != nil {
+				span.SetError(err)
+				return res, err
+			}
+
+			span.SetAttribute("http.status_code", res.StatusCode)
+			if res.StatusCode >= http.StatusBadRequest {
+				span.SetError(fmt.Errorf("unexpected status code %d", res.StatusCode))
+			}
+
+			return res, nil
+		})
+	}
+}
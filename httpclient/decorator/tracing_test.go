@@ -0,0 +1,57 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/tracing"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestTracingDecorator(c *C) {
+	var buf bytes.Buffer
+	tracer := tracing.NewTracer(tracing.NewJSONExporter(&buf))
+
+	server := httpclient.ClientFunc(func(r *http.Request) (*http.Response, error) {
+		c.Assert(tracing.SpanFromContext(r.Context()), NotNil, Commentf("request span must be passed in context"))
+		if r.URL.Path == "/_tasks" {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+	httpClient := httpclient.Decorate(server, TracingDecorator(tracer))
+
+	ctx, scrape := tracer.Start(context.Background(), "scrape")
+	for _, path := range []string{"/_nodes/_local/stats?filter_path=nodes.*.name&api_key=abc", "/_tasks"} {
+		r, _ := http.NewRequest("GET", path, nil)
+		httpClient.Do(r.WithContext(ctx))
+	}
+	scrape.End()
+
+	var spans []tracing.SpanData
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var span tracing.SpanData
+		c.Assert(dec.Decode(&span), IsNil)
+		spans = append(spans, span)
+	}
+
+	c.Assert(spans, HasLen, 3)
+	for _, span := range spans[:2] {
+		c.Assert(span.TraceID, Equals, spans[2].TraceID)
+		c.Assert(span.ParentSpanID, Equals, spans[2].SpanID)
+	}
+
+	c.Assert(spans[0].Name, Equals, "elasticsearch GET /_nodes/stats")
+	c.Assert(spans[0].Status, Equals, tracing.StatusOK)
+	c.Assert(spans[0].Attributes["http.status_code"], Equals, float64(200))
+	c.Assert(spans[0].Attributes["http.url"], Equals, "/_nodes/_local/stats?api_key=%5BREDACTED%5D&filter_path=nodes.%2A.name")
+
+	c.Assert(spans[1].Name, Equals, "elasticsearch GET /_tasks")
+	c.Assert(spans[1].Status, Equals, tracing.StatusError)
+	c.Assert(spans[1].Error, Equals, "connection refused")
+}
@@ -0,0 +1,200 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxFileNamePathLength limits length of request path part of exchange file name
+const maxFileNamePathLength = 100
+
+var (
+	// unsafeFileNameChars are replaced in request path part of exchange file name
+	unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+	// volatileTimestamps match ISO 8601 date times and epoch seconds or millis of 2001-2033 in request bodies,
+	// e.g. rendered time placeholders of queries
+	volatileTimestamps = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?|\b1\d{9}(?:\d{3})?\b`)
+)
+
+// Exchange is a recorded request and response. Exchanges are stored as JSON files
+// by decorator.RecordDecorator and served by ReplayClient
+type Exchange struct {
+	Method      string `json:"method"`
+	URI         string `json:"uri"`
+	RequestBody string `json:"request_body,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	// Body is a JSON response body, it's stored as is to keep fixtures readable
+	Body json.RawMessage `json:"body,omitempty"`
+	// Text is a response body which isn't a JSON object or array, e.g. a response of "_cat" API
+	Text string `json:"text,omitempty"`
+}
+
+// NewExchange returns exchange of given request and response with already read bodies
+func NewExchange(r *http.Request, requestBody []byte, res *http.Response, responseBody []byte) *Exchange {
+	e := &Exchange{
+		Method:      r.Method,
+		URI:         canonicalURI(r.URL),
+		RequestBody: string(requestBody),
+		Status:      res.StatusCode,
+		ContentType: res.Header.Get("Content-Type"),
+	}
+
+	trimmed := bytes.TrimSpace(responseBody)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		e.Body = trimmed
+	} else {
+		e.Text = string(responseBody)
+	}
+
+	return e
+}
+
+// FileName returns name of exchange file. Exchanges of requests with the same method, path, query and body
+// have the same name, so the last recorded one is replayed. Hash of the request is added to readable path
+// to distinguish requests with different queries and bodies. Timestamps in bodies and bodies of PUT requests
+// are ignored, so requests repeated on each scrape are recorded to the same file, see requestBodyKey
+func (e *Exchange) FileName() string {
+	return exchangeFileName(e.Method, e.URI, e.RequestBody)
+}
+
+// Response returns recorded response to given request
+func (e *Exchange) Response(r *http.Request) *http.Response {
+	body := []byte(e.Text)
+	if len(e.Body) > 0 {
+		body = e.Body
+	}
+
+	header := http.Header{}
+	if e.ContentType != "" {
+		header.Set("Content-Type", e.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
+}
+
+// WriteFile writes exchange to its file in given directory. The file is replaced atomically,
+// so concurrent writes of the same request and reads by ReplayClient never see partial files
+func (e *Exchange) WriteFile(dir string) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".exchange-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, e.FileName()))
+}
+
+// ReplayClient is a Client serving responses recorded by decorator.RecordDecorator,
+// so exporter output for a captured cluster can be reproduced offline
+type ReplayClient struct {
+	dir string
+}
+
+// NewReplayClient returns client serving exchanges recorded to given directory
+func NewReplayClient(dir string) *ReplayClient {
+	return &ReplayClient{dir: dir}
+}
+
+// Do returns recorded response to request with the same method, path, query and body.
+// Host of the request and timestamps in the body are ignored, requests without recorded exchange fail
+func (c *ReplayClient) Do(r *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if r.Body != nil {
+		var err error
+		requestBody, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	uri := canonicalURI(r.URL)
+	data, err := ioutil.ReadFile(filepath.Join(c.dir, exchangeFileName(r.Method, uri, string(requestBody))))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recorded response for %s %s in %s", r.Method, uri, c.dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var e Exchange
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("invalid recorded response for %s %s: %s", r.Method, uri, err)
+	}
+
+	return e.Response(r), nil
+}
+
+// canonicalURI returns path and sorted query of URL
+func canonicalURI(u *url.URL) string {
+	uri := u.EscapedPath()
+	if query := u.Query(); len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	return uri
+}
+
+// exchangeFileName returns name of exchange file, e.g. "GET_nodes_local_stats-3f2a9c1b6d7e8f90.json"
+func exchangeFileName(method, uri, requestBody string) string {
+	hash := sha256.Sum256([]byte(method + " " + uri + "\n" + requestBodyKey(method, requestBody)))
+
+	path := uri
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.Trim(unsafeFileNameChars.ReplaceAllString(path, "_"), "_")
+	if len(path) > maxFileNamePathLength {
+		path = path[:maxFileNamePathLength]
+	}
+
+	name := method
+	if path != "" {
+		name += "_" + path
+	}
+
+	return name + "-" + hex.EncodeToString(hash[:8]) + ".json"
+}
+
+// requestBodyKey returns part of request body identifying the request. Searches render relative time
+// ranges on each request, so timestamps are replaced. PUT requests write documents, e.g. canary ones
+// with a timestamp and sequence number, their responses don't depend on the body, so it's ignored
+func requestBodyKey(method, requestBody string) string {
+	if method == http.MethodPut {
+		return ""
+	}
+	return volatileTimestamps.ReplaceAllString(requestBody, "{{timestamp}}")
+}
@@ -7,14 +7,25 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/canary"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/custom"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/freshness"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indexgroup"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/indices"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/query"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/collector/tasks"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/config"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/elasticsearch"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/encryption"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient"
 	"github.com/monitoring-tools/prom-elasticsearch-exporter/httpclient/decorator"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/metrics"
+	"github.com/monitoring-tools/prom-elasticsearch-exporter/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -31,12 +42,65 @@ The commands & flags are:
 
   --web.listen-address      address to listen on for web interface and telemetry. Default - :9108
   --web.telemetry-path      path under which to expose metrics. Default - /metrics
+  --web.pedantic            serve metrics through pedantic registry which fails scrape if collected metrics are not
+                            consistent with their descriptions. Use for development. Default - false
+  --web.enable-admin-api    serve administrative endpoints, e.g. POST /-/debug-log. They are not authenticated,
+                            so enable it only if the listen address isn't reachable by untrusted clients. Default - false
+  --config.file             path to JSON configuration file with index groups, custom metrics, queries, freshness checks and canary probes. Optional
   --es.timeout              timeout for trying to get stats from ElasticSearch. Default - 5s
   --es.uri                  ElasticSearch node URI. Default - http://localhost:9200
   --es.all                  export stats for all nodes in the cluster. Default - false
   --es.ca                   path to PEM file that conains trusted CAs for the ElasticSearch connection
   --es.client-private-key   path to PEM file that conains the private key for client auth when connecting to ElasticSearch
   --es.client-cert          path to PEM file that conains the corresponding cert for the private key to connect to Elasticsearch
+  --es.compression          request gzip or deflate compressed responses from ElasticSearch. Default - true
+  --es.debug-log            log method, URL, status, latency and bodies of ElasticSearch requests. Bodies are truncated and
+                            secret-like fields are redacted. Can be switched at runtime with POST /-/debug-log?enabled=true|false
+                            if --web.enable-admin-api is set.
+                            Default - false
+  --es.circuit-breaker.failures
+                            number of consecutive failed (transport errors, 429 and 5xx responses) or slow requests
+                            to an endpoint which open its circuit breaker. Collectors skip the endpoint while the breaker
+                            is open. Default - 0 (disabled)
+  --es.circuit-breaker.slow-threshold
+                            duration until response headers after which request is counted as failed by circuit breaker.
+                            Default - 0 (disabled)
+  --es.circuit-breaker.cool-down
+                            time circuit breaker stays open before a probe request is let through. Default - 1m
+  --es.record-dir           directory to write every ElasticSearch request and response to as a JSON file, e.g. to capture
+                            a cluster for offline debugging. Default - disabled
+  --es.replay-dir           directory with recorded requests to serve responses from instead of requesting ElasticSearch,
+                            e.g. to reproduce exporter output of a captured cluster. Default - disabled
+  --tracing.output          write trace spans of scrapes, collectors and ElasticSearch requests as JSON lines to "stdout"
+                            or given file. Default - disabled
+  --es.indices.include      index name pattern to export stats for. Wildcards ("logs-*") and regular expressions in slashes
+                            ("/^logs-[0-9]+$/") are supported. Can be repeated. Default - all indices
+  --es.indices.exclude      index name pattern to skip. Same syntax as for --es.indices.include. Can be repeated
+  --es.indices.hidden       export stats for hidden and system (dot-prefixed) indices. Default - true
+  --es.indices.top-n        export full stats only for top N indices (or index groups), the rest is merged into index="_other".
+                            Default - 0 (disabled)
+  --es.indices.top-n-by     top N ranking key: store_size, docs, search_rate, indexing_rate. Default - store_size
+  --es.indices.max-series   hard limit of index series exported per scrape. Default - 0 (unlimited)
+  --es.cluster-health.shards
+                            export health of each shard. Default - false
+  --es.aliases.stats        export index stats summed over indices of each alias. Default - false
+  --es.tasks.top-n          export descriptions of top N longest running tasks. Default - 0 (disabled)
+  --es.tasks.duration-buckets
+                            comma-separated upper bounds of task duration histogram buckets in seconds.
+                            Default - 0.1,1,5,10,30,60,300,900,1800,3600,7200
+  --es.tasks.long-running-threshold
+                            count tasks running longer than the threshold, e.g. 10m. Default - 0 (disabled)
+  --es.recovery.track       track completed shard recoveries to export their durations. Requests all recoveries
+                            instead of active ones only. Default - false
+  --es.lifecycle            export ILM state of managed indices, ISM state on OpenSearch. Default - false
+  --es.compatible-with      send REST API compatibility headers to Elasticsearch of newer major version, e.g. 7 for 8.x
+                            clusters. Default - 0 (disabled)
+  --es.version.refresh-interval
+                            interval of cluster distribution and version re-detection. Metrics which don't exist
+                            on the detected version are skipped. Default - 5m
+  --es.index-stats.<group>  enable or disable export of index stats group. Groups enabled by default - docs, store, indexing,
+                            get, search, merges, refresh, query_cache, request_cache, fielddata, segments, translog.
+                            Groups disabled by default - flush, warmer, completion, segments_memory, recovery
 `
 
 // Variables passed through ldflags
@@ -50,14 +114,55 @@ func main() {
 	var (
 		listenAddress      = flag.String("web.listen-address", ":9108", "Address to listen on for web interface and telemetry")
 		metricsPath        = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics")
+		webPedantic        = flag.Bool("web.pedantic", false, "Check that exported metrics are consistent with their descriptions")
+		webAdminAPI        = flag.Bool("web.enable-admin-api", false, "Serve unauthenticated administrative endpoints, e.g. /-/debug-log")
+		configFile         = flag.String("config.file", "", "Path to JSON configuration file")
 		esTimeout          = flag.Duration("es.timeout", 5*time.Second, "Timeout for trying to get stats from ElasticSearch")
 		esURI              = flag.String("es.uri", "http://localhost:9200", "HTTP API address of an Elasticsearch node")
 		esAllNodes         = flag.Bool("es.all", false, "Export stats for all nodes in the cluster")
 		esCA               = flag.String("es.ca", "", "Path to PEM file that conains trusted CAs for the ElasticSearch connection")
 		esClientPrivateKey = flag.String("es.client-private-key", "", "Path to PEM file that conains the private key for client auth when connecting to ElasticSearch")
 		esClientCert       = flag.String("es.client-cert", "", "Path to PEM file that conains the corresponding cert for the private key to connect to ElasticSearch")
+		esCompression      = flag.Bool("es.compression", true, "Request compressed responses from ElasticSearch")
+		esDebugLog         = flag.Bool("es.debug-log", false, "Log ElasticSearch requests and responses, can be switched at runtime on /-/debug-log with admin API enabled")
+		esBreakerFailures  = flag.Int("es.circuit-breaker.failures", 0, "Consecutive failed or slow requests to an endpoint which stop requesting it for a cool-down period")
+		esBreakerSlow      = flag.Duration("es.circuit-breaker.slow-threshold", 0, "Duration after which request is counted as failed by circuit breaker")
+		esBreakerCoolDown  = flag.Duration("es.circuit-breaker.cool-down", time.Minute, "Time circuit breaker stays open before probing the endpoint")
+		esRecordDir        = flag.String("es.record-dir", "", "Directory to record ElasticSearch requests and responses to")
+		esReplayDir        = flag.String("es.replay-dir", "", "Directory to replay recorded ElasticSearch responses from instead of requesting ElasticSearch")
+		tracingOutput      = flag.String("tracing.output", "", "Write trace spans of scrapes and ElasticSearch requests as JSON lines to stdout or given file")
+		esIndicesHidden    = flag.Bool("es.indices.hidden", true, "Export stats for hidden and system indices")
+		esIndicesTopN      = flag.Int("es.indices.top-n", 0, "Export full stats only for top N indices")
+		esIndicesTopNBy    = flag.String("es.indices.top-n-by", indices.RankByStoreSize, "Top N indices ranking key")
+		esIndicesMaxSeries = flag.Int("es.indices.max-series", 0, "Hard limit of index series exported per scrape")
+		esHealthShards     = flag.Bool("es.cluster-health.shards", false, "Export health of each shard")
+		esAliasesStats     = flag.Bool("es.aliases.stats", false, "Export index stats summed over indices of each alias")
+		esRecoveryTrack    = flag.Bool("es.recovery.track", false, "Track completed shard recoveries to export their durations")
+		esLifecycle        = flag.Bool("es.lifecycle", false, "Export ILM (ISM on OpenSearch) state of managed indices")
+		esTasksTopN        = flag.Int("es.tasks.top-n", 0, "Export descriptions of top N longest running tasks")
+		esTasksLongRunning = flag.Duration("es.tasks.long-running-threshold", 0, "Count tasks running longer than the threshold")
+		esCompatibleWith   = flag.Int("es.compatible-with", 0, "Request responses of given major version from newer Elasticsearch")
+		esVersionRefresh   = flag.Duration("es.version.refresh-interval", elasticsearch.DefaultVersionRefreshInterval, "Interval of cluster version re-detection")
+
+		esTasksDurationBuckets = float64sFlag(tasks.DefaultDurationBuckets)
+
+		esIndicesInclude stringsFlag
+		esIndicesExclude stringsFlag
 	)
 
+	flag.Var(&esIndicesInclude, "es.indices.include", "Index name pattern to export stats for")
+	flag.Var(&esIndicesExclude, "es.indices.exclude", "Index name pattern to skip")
+	flag.Var(&esTasksDurationBuckets, "es.tasks.duration-buckets", "Comma-separated upper bounds of task duration histogram buckets in seconds")
+
+	indexStatsGroups := make(map[string]*bool, len(indices.StatsGroups))
+	for _, group := range indices.StatsGroups {
+		indexStatsGroups[group.Name] = flag.Bool(
+			"es.index-stats."+group.Name,
+			group.EnabledByDefault,
+			fmt.Sprintf("Export %s index stats", group.Name),
+		)
+	}
+
 	flag.Usage = func() { printUsage() }
 	flag.Parse()
 
@@ -76,29 +181,191 @@ func main() {
 		}
 	}
 
+	cfg := &config.Config{}
+	if *configFile != "" {
+		var err error
+		if cfg, err = config.Load(*configFile); err != nil {
+			log.Fatalln("Unable to load configuration file:", err)
+		}
+	}
+
 	// returns nil if not provided and falls back to simple TCP.
 	tlsConfig := encryption.CreateTLSConfig(*esCA, *esClientCert, *esClientPrivateKey)
 
-	httpClient := &http.Client{
+	var httpClient httpclient.Client = &http.Client{
 		Timeout: *esTimeout,
 		Transport: &http.Transport{
 			TLSClientConfig: tlsConfig,
 		},
 	}
+	if *esRecordDir != "" && *esReplayDir != "" {
+		log.Fatalln("Recording and replaying of ElasticSearch responses can't be enabled together")
+	}
+	if *esReplayDir != "" {
+		log.Println("Replaying ElasticSearch responses from:", *esReplayDir)
+		httpClient = httpclient.NewReplayClient(*esReplayDir)
+	}
+
+	registry := prometheus.NewRegistry()
+	if *webPedantic {
+		registry = prometheus.NewPedanticRegistry()
+	}
+	registry.MustRegister(
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		prometheus.NewGoCollector(),
+	)
+
+	// ES API requests are instrumented before decompression, so response sizes are counted as received
+	requestMetrics := decorator.NewRequestMetrics()
+	registry.MustRegister(requestMetrics)
+
+	// decorators are listed from the innermost one, requests pass them in reverse order
+	decorators := []httpclient.DecoratorFunc{
+		decorator.InstrumentationDecorator(requestMetrics),
+	}
+	if *esCompression {
+		compressedBytes := metrics.NewCounter(
+			"exporter", "response_compressed_bytes_total", "Total size of ElasticSearch response bodies received over the network",
+		)
+		uncompressedBytes := metrics.NewCounter(
+			"exporter", "response_uncompressed_bytes_total", "Total size of ElasticSearch response bodies after decompression",
+		)
+		registry.MustRegister(compressedBytes, uncompressedBytes)
+		decorators = append(decorators, decorator.CompressionDecorator(compressedBytes, uncompressedBytes))
+	}
+
+	// responses are recorded and logged after decompression
+	if *esRecordDir != "" {
+		if err := os.MkdirAll(*esRecordDir, 0755); err != nil {
+			log.Fatalln("Unable to create record directory:", err)
+		}
+		decorators = append(decorators, decorator.RecordDecorator(*esRecordDir))
+	}
+
+	debugLog := decorator.NewDebugLog(*esDebugLog, decorator.DefaultDebugLogBodyLimit)
+	decorators = append(decorators, decorator.DebugLogDecorator(debugLog))
+
+	// rejected requests aren't counted as ES requests, but they are traced
+	if *esBreakerFailures > 0 {
+		circuitBreaker := decorator.NewCircuitBreaker(decorator.CircuitBreakerConfig{
+			Failures:      *esBreakerFailures,
+			SlowThreshold: *esBreakerSlow,
+			CoolDown:      *esBreakerCoolDown,
+		})
+		registry.MustRegister(circuitBreaker)
+		decorators = append(decorators, decorator.CircuitBreakerDecorator(circuitBreaker))
+	}
+
+	var tracer *tracing.Tracer
+	if *tracingOutput != "" {
+		output := os.Stdout
+		if *tracingOutput != "stdout" {
+			var err error
+			if output, err = os.OpenFile(*tracingOutput, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+				log.Fatalln("Unable to open tracing output:", err)
+			}
+		}
+		tracer = tracing.NewTracer(tracing.NewJSONExporter(output))
+		decorators = append(decorators, decorator.TracingDecorator(tracer))
+	}
 
-	decoratedClient := httpclient.Decorate(
-		httpClient,
+	decorators = append(
+		decorators,
 		decorator.BaseURLDecorator(*esURI),
 		decorator.RecoverDecorator(), // better to place it last to recover panics from decorators too
 	)
 
-	prometheus.MustRegister(collector.NewCompositeCollector(
-		elasticsearch.NewClient(decoratedClient),
-		*esAllNodes,
-		version, goVersion, gitBranch,
-	))
+	decoratedClient := httpclient.Decorate(httpClient, decorators...)
+
+	esClientOptions := []elasticsearch.Option{
+		elasticsearch.WithVersionRefreshInterval(*esVersionRefresh),
+	}
+	if *esCompatibleWith > 0 {
+		esClientOptions = append(esClientOptions, elasticsearch.WithCompatibility(*esCompatibleWith))
+	}
+	if len(esIndicesInclude) > 0 || len(esIndicesExclude) > 0 || !*esIndicesHidden {
+		indexFilter, err := elasticsearch.NewIndexFilter(esIndicesInclude, esIndicesExclude, *esIndicesHidden)
+		if err != nil {
+			log.Fatalln("Invalid index filter:", err)
+		}
+		esClientOptions = append(esClientOptions, elasticsearch.WithIndexFilter(indexFilter))
+	}
+
+	indexGrouper, err := indexgroup.New(cfg.IndexGroups)
+	if err != nil {
+		log.Fatalln("Invalid index groups configuration:", err)
+	}
+
+	indicesConfig := indices.Config{
+		Grouper:    indexGrouper,
+		TopN:       *esIndicesTopN,
+		TopNBy:     *esIndicesTopNBy,
+		MaxSeries:  *esIndicesMaxSeries,
+		AliasStats: *esAliasesStats,
+	}
+	for _, group := range indices.StatsGroups {
+		if *indexStatsGroups[group.Name] {
+			indicesConfig.EnabledGroups = append(indicesConfig.EnabledGroups, group.Name)
+		}
+	}
+	if err := indicesConfig.Validate(); err != nil {
+		log.Fatalln("Invalid indices configuration:", err)
+	}
+
+	tasksConfig := tasks.Config{
+		TopN:                 *esTasksTopN,
+		DurationBuckets:      esTasksDurationBuckets,
+		LongRunningThreshold: *esTasksLongRunning,
+	}
+	if err := tasksConfig.Validate(); err != nil {
+		log.Fatalln("Invalid tasks configuration:", err)
+	}
+
+	freshnessTargets, err := freshness.NewTargets(cfg.Freshness)
+	if err != nil {
+		log.Fatalln("Invalid freshness configuration:", err)
+	}
 
-	http.Handle(*metricsPath, promhttp.Handler())
+	var canaryProbe *canary.Probe
+	if cfg.Canary != nil {
+		if canaryProbe, err = canary.NewProbe(*cfg.Canary); err != nil {
+			log.Fatalln("Invalid canary configuration:", err)
+		}
+	}
+
+	collectorConfig := collector.Config{
+		ExportMetricsForAllNodes: *esAllNodes,
+		ClusterHealthShards:      *esHealthShards,
+		Tasks:                    tasksConfig,
+		TrackRecoveries:          *esRecoveryTrack,
+		Lifecycle:                *esLifecycle,
+		Indices:                  indicesConfig,
+		Freshness:                freshnessTargets,
+		Canary:                   canaryProbe,
+		Tracer:                   tracer,
+		AppVersion:               version,
+		GoVersion:                goVersion,
+		GitBranch:                gitBranch,
+	}
+
+	// queries and custom metrics are checked for conflicts with metrics of enabled built-in collectors and each other
+	if collectorConfig.Queries, err = query.NewQueries(cfg.Queries, collector.MetricNames(collectorConfig)); err != nil {
+		log.Fatalln("Invalid queries configuration:", err)
+	}
+	if collectorConfig.CustomMetrics, err = custom.NewMetrics(cfg.CustomMetrics, collector.MetricNames(collectorConfig)); err != nil {
+		log.Fatalln("Invalid custom metrics configuration:", err)
+	}
+
+	esClient := elasticsearch.NewClient(decoratedClient, esClientOptions...)
+	// version is detected at startup to choose request paths and metrics, and re-detected on scrapes later
+	esClient.Version()
+
+	registry.MustRegister(collector.NewCompositeCollector(esClient, collectorConfig))
+
+	http.Handle(*metricsPath, promhttp.InstrumentMetricHandler(registry, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	if *webAdminAPI {
+		http.HandleFunc("/-/debug-log", DebugLogHandler(debugLog))
+	}
 	http.HandleFunc("/", IndexHandler(*metricsPath))
 
 	log.Println("Listening on:", formatListenAddr(*listenAddress))
@@ -108,6 +375,42 @@ func main() {
 	}
 }
 
+// stringsFlag is a flag.Value that collects values of repeated flag
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// float64sFlag is a flag.Value with comma-separated list of numbers
+type float64sFlag []float64
+
+func (f *float64sFlag) String() string {
+	values := make([]string, len(*f))
+	for i, v := range *f {
+		values[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(values, ",")
+}
+
+func (f *float64sFlag) Set(value string) error {
+	var values []float64
+	for _, s := range strings.Split(value, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+	}
+	*f = values
+	return nil
+}
+
 // IndexHandler returns a http handler with the correct metricsPath
 func IndexHandler(metricsPath string) http.HandlerFunc {
 	indexHTML := `
@@ -130,6 +433,29 @@ func IndexHandler(metricsPath string) http.HandlerFunc {
 	}
 }
 
+// DebugLogHandler returns handler of admin endpoint, which shows state of ES requests debug log on GET
+// and switches it on POST or PUT with "enabled" parameter
+func DebugLogHandler(debugLog *decorator.DebugLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+			if err != nil {
+				http.Error(w, "invalid \"enabled\" parameter: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			debugLog.SetEnabled(enabled)
+			log.Println("Debug log of ElasticSearch requests enabled:", enabled)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		fmt.Fprintf(w, "enabled=%t\n", debugLog.Enabled())
+	}
+}
+
 // formatListenAddr returns formatted UNIX addr
 func formatListenAddr(addr string) string {
 	parts := strings.Split(addr, ":")
@@ -140,6 +466,6 @@ func formatListenAddr(addr string) string {
 }
 
 func printUsage() {
-	fmt.Println(usage)
+	fmt.Print(usage)
 	os.Exit(0)
 }
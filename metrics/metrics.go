@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"regexp"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -8,6 +10,9 @@ const (
 	namespace = "elasticsearch"
 )
 
+// descNameRe extracts name from description, prometheus.Desc doesn't expose it
+var descNameRe = regexp.MustCompile(`fqName: "([^"]*)"`)
+
 // New creates new metric
 func New(t prometheus.ValueType, subsystem, name, help string, labels []string) *Metric {
 	return &Metric{
@@ -24,10 +29,23 @@ func NewWithConstLabels(t prometheus.ValueType, subsystem, name, help string, la
 	}
 }
 
+// FQName returns fully-qualified name of exported metric
+func FQName(subsystem, name string) string {
+	return prometheus.BuildFQName(namespace, subsystem, name)
+}
+
+// DescName returns fully-qualified name of described metric
+func DescName(desc *prometheus.Desc) string {
+	if m := descNameRe.FindStringSubmatch(desc.String()); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
 // NewDesc returns new metric description in terms of Prometheus client
 func NewDesc(subsystem, name, help string, labels []string, constLabels prometheus.Labels) *prometheus.Desc {
 	return prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, subsystem, name),
+		FQName(subsystem, name),
 		help,
 		labels, constLabels,
 	)
@@ -48,3 +66,55 @@ func (m *Metric) Type() prometheus.ValueType {
 func (m *Metric) Desc() *prometheus.Desc {
 	return m.desc
 }
+
+// NewCounter returns new counter for values counted by exporter itself
+func NewCounter(subsystem, name, help string) prometheus.Counter {
+	return prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+		},
+	)
+}
+
+// NewCounterVec returns new counter vector for values counted by exporter itself
+func NewCounterVec(subsystem, name, help string, labels []string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+		},
+		labels,
+	)
+}
+
+// NewGaugeVec returns new gauge vector for values set by exporter itself
+func NewGaugeVec(subsystem, name, help string, labels []string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+		},
+		labels,
+	)
+}
+
+// NewHistogramVec returns new histogram vector for metrics observed by exporter itself
+func NewHistogramVec(subsystem, name, help string, buckets []float64, labels []string) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+			Buckets:   buckets,
+		},
+		labels,
+	)
+}
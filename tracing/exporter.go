@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+)
+
+// JSONExporter writes spans to writer as JSON, one span per line
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONExporter returns new exporter writing spans to given writer, e.g. stdout or a file
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+// Export writes span as a JSON line
+func (e *JSONExporter) Export(span SpanData) error {
+	line, err := json.Marshal(span)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err = e.w.Write(append(line, '\n'))
+	return err
+}
+
+// logExportError logs failed export, spans are dropped instead of failing traced operations
+func logExportError(err error) {
+	log.Println("ERROR: failed to export trace span: ", err)
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestTracer_Start(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewJSONExporter(&buf))
+
+	ctx, root := tracer.Start(context.Background(), "scrape")
+	_, child := tracer.Start(ctx, "collect nodes")
+	child.SetAttribute("endpoint", "/_nodes/stats")
+	child.SetError(errors.New("timeout"))
+	child.End()
+	child.End()
+	root.End()
+
+	var spans []SpanData
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var span SpanData
+		if err := dec.Decode(&span); err != nil {
+			t.Fatalf("Error on decoding exported span: %s", err)
+		}
+		spans = append(spans, span)
+	}
+
+	if len(spans) != 2 {
+		t.Fatalf("Unexpected exported spans count, want 2, got %d", len(spans))
+	}
+	got, parent := spans[0], spans[1]
+	if got.Name != "collect nodes" || parent.Name != "scrape" {
+		t.Fatalf("Unexpected span names: %s, %s", got.Name, parent.Name)
+	}
+	if got.TraceID != parent.TraceID || got.ParentSpanID != parent.SpanID || parent.ParentSpanID != "" {
+		t.Fatalf("Span %+v is not a child of span %+v", got, parent)
+	}
+	if got.Status != StatusError || got.Error != "timeout" || got.Attributes["endpoint"] != "/_nodes/stats" {
+		t.Fatalf("Unexpected span: %+v", got)
+	}
+	if got.EndTime.Before(got.StartTime) {
+		t.Fatalf("Span ended before start: %+v", got)
+	}
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+
+	ctx, span := tracer.Start(context.Background(), "scrape")
+	span.SetAttribute("cluster", "test")
+	span.SetError(errors.New("failed"))
+	span.End()
+
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatalf("Nil tracer must not start spans")
+	}
+}